@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/systemschema"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/covering"
@@ -786,6 +787,11 @@ func backupPlanHook(
 		// of requiring full backups after schema changes remains.
 		descriptorProtos := make([]descpb.Descriptor, 0, len(targetDescs))
 		for _, desc := range targetDescs {
+			if sc, ok := desc.(catalog.SchemaDescriptor); ok {
+				descriptorProtos = append(descriptorProtos,
+					schemadesc.NewImmutable(*sc.SchemaDesc()).BackupManifestEntry())
+				continue
+			}
 			descriptorProtos = append(descriptorProtos, *desc.DescriptorProto())
 		}
 
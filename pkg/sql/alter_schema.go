@@ -17,7 +17,6 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkeys"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
@@ -78,14 +77,12 @@ func (p *planner) alterSchemaOwner(
 	newOwner string,
 	jobDesc string,
 ) error {
-	privs := scDesc.GetPrivileges()
-
 	hasOwnership, err := p.HasOwnership(ctx, scDesc)
 	if err != nil {
 		return err
 	}
 
-	if err := p.checkCanAlterToNewOwner(ctx, scDesc, privs, newOwner, hasOwnership); err != nil {
+	if err := p.checkCanAlterToNewOwner(ctx, scDesc, scDesc.GetPrivileges(), newOwner, hasOwnership); err != nil {
 		return err
 	}
 
@@ -95,12 +92,12 @@ func (p *planner) alterSchemaOwner(
 	}
 
 	// If the owner we want to set to is the current owner, do a no-op.
-	if newOwner == privs.Owner {
+	if newOwner == scDesc.GetOwner() {
 		return nil
 	}
 
 	// Update the owner of the schema.
-	privs.SetOwner(newOwner)
+	scDesc.SetOwner(newOwner)
 
 	return p.writeSchemaDescChange(ctx, scDesc, jobDesc)
 }
@@ -117,15 +114,11 @@ func (p *planner) renameSchema(
 		return pgerror.Newf(pgcode.DuplicateSchema, "schema %q already exists", newName)
 	}
 
-	// Ensure that the new name is a valid schema name.
-	if err := schemadesc.IsSchemaNameValid(newName); err != nil {
+	// Rename the schema and update the schema mapping in the parent database.
+	if err := schemadesc.RenameWrites(desc, db, newName); err != nil {
 		return err
 	}
 
-	// Set the new name for the descriptor.
-	oldName := desc.Name
-	desc.SetName(newName)
-
 	// Write a new namespace entry for the new name.
 	nameKey := catalogkeys.NewSchemaKey(desc.ParentID, newName).Key(p.execCfg.Codec)
 	b := p.txn.NewBatch()
@@ -137,35 +130,6 @@ func (p *planner) renameSchema(
 		return err
 	}
 
-	// Update the schema mapping in the parent database.
-
-	// First, ensure that the new name isn't present, and that we have an entry
-	// for the old name.
-	_, oldPresent := db.Schemas[oldName]
-	_, newPresent := db.Schemas[newName]
-	if !oldPresent {
-		return errors.AssertionFailedf(
-			"old name %q not present in database schema mapping",
-			oldName,
-		)
-	}
-	if newPresent {
-		return errors.AssertionFailedf(
-			"new name %q already present in database schema mapping",
-			newName,
-		)
-	}
-
-	// Mark the old schema name as dropped.
-	db.Schemas[oldName] = descpb.DatabaseDescriptor_SchemaInfo{
-		ID:      desc.ID,
-		Dropped: true,
-	}
-	// Create an entry for the new schema name.
-	db.Schemas[newName] = descpb.DatabaseDescriptor_SchemaInfo{
-		ID:      desc.ID,
-		Dropped: false,
-	}
 	if err := p.writeNonDropDatabaseChange(
 		ctx, db,
 		fmt.Sprintf("updating parent database %s for %s", db.GetName(), jobDesc),
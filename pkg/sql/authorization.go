@@ -554,6 +554,12 @@ func (p *planner) checkCanAlterToNewOwner(
 			"must be owner of %s %s", tree.Name(objType), tree.Name(desc.GetName()))
 	}
 
+	// Schemas follow the Postgres rule, under which the current user rather
+	// than the current owner must be a member of the new owning role.
+	if scDesc, ok := desc.(*schemadesc.Mutable); ok {
+		return scDesc.ValidateOwnerChange(ctx, p.User(), newOwner, p)
+	}
+
 	// Requirements from PG:
 	// To alter the owner, you must also be a direct or indirect member of the
 	// new owning role, and that role must have CREATE privilege on the
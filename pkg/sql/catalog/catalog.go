@@ -11,6 +11,8 @@
 package catalog
 
 import (
+	"context"
+
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 )
@@ -39,6 +41,40 @@ type MutableDescriptor interface {
 	IsNew() bool
 }
 
+// DescriptorType identifies the type of a descriptor. Its values match those
+// of Descriptor.TypeName.
+type DescriptorType string
+
+const (
+	// Database is the DescriptorType of database descriptors.
+	Database DescriptorType = "database"
+	// Table is the DescriptorType of table, view and sequence descriptors.
+	Table DescriptorType = "relation"
+	// Type is the DescriptorType of type descriptors.
+	Type DescriptorType = "type"
+	// Schema is the DescriptorType of schema descriptors.
+	Schema DescriptorType = "schema"
+)
+
+// DescriptorBuilder builds descriptors of one type from their protobuf
+// representation, so that descriptors can be constructed uniformly.
+type DescriptorBuilder interface {
+	// DescriptorType returns the type of the descriptors built.
+	DescriptorType() DescriptorType
+	// RunPostDeserializationChanges upgrades the descriptor after it has been
+	// read from storage, e.g. by fixing its privileges. It must be called
+	// before any of the Build methods if the descriptor was deserialized.
+	RunPostDeserializationChanges(ctx context.Context, dg DescGetter) error
+	// BuildImmutable returns an immutable descriptor.
+	BuildImmutable() Descriptor
+	// BuildExistingMutable returns a mutable descriptor for a descriptor which
+	// already exists in storage.
+	BuildExistingMutable() MutableDescriptor
+	// BuildCreatedMutable returns a mutable descriptor for a descriptor which is
+	// created in the current transaction.
+	BuildCreatedMutable() MutableDescriptor
+}
+
 // VirtualSchemas is a collection of VirtualSchemas.
 type VirtualSchemas interface {
 	GetVirtualSchema(schemaName string) (VirtualSchema, bool)
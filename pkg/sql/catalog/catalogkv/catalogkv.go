@@ -309,7 +309,17 @@ func unwrapDescriptor(
 	case typ != nil:
 		return typedesc.NewImmutable(*typ), nil
 	case schema != nil:
-		return schemadesc.NewImmutable(*schema), nil
+		b := schemadesc.NewBuilder(schema)
+		if err := b.RunPostDeserializationChanges(ctx, dg); err != nil {
+			return nil, err
+		}
+		schemaDesc := b.BuildImmutableSchema()
+		var errs catalog.ValidationErrors
+		schemaDesc.ValidateIdentity(&errs)
+		if err := errs.CombinedError(); err != nil {
+			return nil, err
+		}
+		return schemaDesc, nil
 	default:
 		return nil, nil
 	}
@@ -344,7 +354,17 @@ func unwrapDescriptorMutable(
 	case typ != nil:
 		return typedesc.NewExistingMutable(*typ), nil
 	case schema != nil:
-		return schemadesc.NewMutableExisting(*schema), nil
+		b := schemadesc.NewBuilder(schema)
+		if err := b.RunPostDeserializationChanges(ctx, dg); err != nil {
+			return nil, err
+		}
+		schemaDesc := b.BuildExistingMutableSchema()
+		var errs catalog.ValidationErrors
+		schemaDesc.ValidateIdentity(&errs)
+		if err := errs.CombinedError(); err != nil {
+			return nil, err
+		}
+		return schemaDesc, nil
 	default:
 		return nil, nil
 	}
@@ -124,6 +124,14 @@ func (a UncachedPhysicalAccessor) GetSchema(
 	if !ok {
 		return false, catalog.ResolvedSchema{}, nil
 	}
+	// Offline schemas are skipped by name resolution, but are still visible to
+	// lookups of mutable descriptors, which are performed by schema changes.
+	if sc.Offline() && !flags.RequireMutable {
+		if flags.Required {
+			return false, catalog.ResolvedSchema{}, catalog.FilterDescriptorState(sc)
+		}
+		return false, catalog.ResolvedSchema{}, nil
+	}
 	return true, catalog.ResolvedSchema{
 		ID:   sc.GetID(),
 		Kind: catalog.SchemaUserDefined,
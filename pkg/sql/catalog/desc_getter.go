@@ -12,6 +12,7 @@ package catalog
 
 import (
 	"context"
+	"sort"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 )
@@ -23,6 +24,40 @@ type DescGetter interface {
 	GetDescs(ctx context.Context, reqs []descpb.ID) ([]Descriptor, error)
 }
 
+// ValidationDescGetter is used by validation and precondition checks which,
+// in addition to looking up descriptors by ID, need to find the objects which
+// live inside of a schema.
+type ValidationDescGetter interface {
+	DescGetter
+	// GetObjectIDsInSchema returns the IDs of the tables, views, sequences and
+	// types whose parent database is parentID and whose parent schema is
+	// parentSchemaID, in ascending order.
+	GetObjectIDsInSchema(ctx context.Context, parentID, parentSchemaID descpb.ID) ([]descpb.ID, error)
+	// GetNamespaceEntries returns the names and IDs of the system.namespace
+	// entries whose parent database is parentID and whose parent schema is
+	// parentSchemaID. This includes the entries of names still being drained.
+	GetNamespaceEntries(
+		ctx context.Context, parentID, parentSchemaID descpb.ID,
+	) (map[string]descpb.ID, error)
+}
+
+// RoleMembershipChecker is used by ownership and privilege checks to expand
+// role memberships.
+type RoleMembershipChecker interface {
+	// MemberOfWithAdminOption returns the roles of which member is a direct or
+	// indirect member, mapped to whether member holds the admin option on
+	// them. member itself is not included.
+	MemberOfWithAdminOption(ctx context.Context, member string) (map[string]bool, error)
+}
+
+// RoleExistenceChecker is used by cross-reference validation to check that the
+// roles referred to by a descriptor's privileges exist. It is implemented by
+// the SQL planner.
+type RoleExistenceChecker interface {
+	// RoleExists returns whether a user or role with the given name exists.
+	RoleExists(ctx context.Context, role string) (bool, error)
+}
+
 // GetTypeDescFromID retrieves the type descriptor for the type ID passed
 // in using an existing descGetter. It returns an error if the descriptor
 // doesn't exist or if it exists and is not a type descriptor.
@@ -71,3 +106,42 @@ func (m MapDescGetter) GetDescs(ctx context.Context, ids []descpb.ID) ([]Descrip
 	}
 	return ret, nil
 }
+
+// GetObjectIDsInSchema implements the catalog.ValidationDescGetter interface.
+func (m MapDescGetter) GetObjectIDsInSchema(
+	ctx context.Context, parentID, parentSchemaID descpb.ID,
+) ([]descpb.ID, error) {
+	var ret descpb.IDs
+	for id, desc := range m {
+		switch desc.(type) {
+		case TableDescriptor, TypeDescriptor:
+		default:
+			continue
+		}
+		if desc.GetParentID() == parentID && desc.GetParentSchemaID() == parentSchemaID {
+			ret = append(ret, id)
+		}
+	}
+	sort.Sort(ret)
+	return ret, nil
+}
+
+// GetNamespaceEntries implements the catalog.ValidationDescGetter interface.
+// The namespace is derived from the names and draining names of the
+// descriptors.
+func (m MapDescGetter) GetNamespaceEntries(
+	ctx context.Context, parentID, parentSchemaID descpb.ID,
+) (map[string]descpb.ID, error) {
+	ret := make(map[string]descpb.ID)
+	for id, desc := range m {
+		if desc.GetParentID() == parentID && desc.GetParentSchemaID() == parentSchemaID {
+			ret[desc.GetName()] = id
+		}
+		for _, ni := range desc.GetDrainingNames() {
+			if ni.ParentID == parentID && ni.ParentSchemaID == parentSchemaID {
+				ret[ni.Name] = id
+			}
+		}
+	}
+	return ret, nil
+}
@@ -194,6 +194,65 @@ func (p *PrivilegeDescriptor) Revoke(
 	}
 }
 
+// findOrCreateObject looks for the default privileges of the given object
+// type and creates them if they are not present. The objects are kept sorted
+// by object type.
+func (p *DefaultPrivilegeDescriptor) findOrCreateObject(
+	objectType privilege.ObjectType,
+) *DefaultPrivilegesForObject {
+	idx := sort.Search(len(p.Objects), func(i int) bool {
+		return p.Objects[i].ObjectType >= string(objectType)
+	})
+	if idx == len(p.Objects) || p.Objects[idx].ObjectType != string(objectType) {
+		p.Objects = append(p.Objects, DefaultPrivilegesForObject{})
+		copy(p.Objects[idx+1:], p.Objects[idx:])
+		p.Objects[idx] = DefaultPrivilegesForObject{ObjectType: string(objectType)}
+	}
+	return &p.Objects[idx]
+}
+
+// Grant adds privileges granted to user by default on the objects of the given
+// type, following the same rules as PrivilegeDescriptor.Grant.
+func (p *DefaultPrivilegeDescriptor) Grant(
+	objectType privilege.ObjectType, user string, privList privilege.List,
+) {
+	obj := p.findOrCreateObject(objectType)
+	privs := PrivilegeDescriptor{Users: obj.Users}
+	privs.Grant(user, privList)
+	obj.Users = privs.Users
+}
+
+// Revoke removes privileges granted to user by default on the objects of the
+// given type, following the same rules as PrivilegeDescriptor.Revoke. The
+// object type is removed once no user is granted privileges on it.
+func (p *DefaultPrivilegeDescriptor) Revoke(
+	objectType privilege.ObjectType, user string, privList privilege.List,
+) {
+	obj := p.findOrCreateObject(objectType)
+	privs := PrivilegeDescriptor{Users: obj.Users}
+	privs.Revoke(user, privList, objectType)
+	obj.Users = privs.Users
+	if len(obj.Users) == 0 {
+		for i := range p.Objects {
+			if p.Objects[i].ObjectType == string(objectType) {
+				p.Objects = append(p.Objects[:i], p.Objects[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// ForObject returns the privileges granted by default on the objects of the
+// given type, or nil if there are none.
+func (p *DefaultPrivilegeDescriptor) ForObject(objectType privilege.ObjectType) []UserPrivileges {
+	for i := range p.Objects {
+		if p.Objects[i].ObjectType == string(objectType) {
+			return p.Objects[i].Users
+		}
+	}
+	return nil
+}
+
 // MaybeFixPrivileges fixes the privilege descriptor if needed, including:
 // * adding default privileges for the "admin" role
 // * fixing default privileges for the "root" user
@@ -83,6 +83,7 @@ type DatabaseDescriptor interface {
 type SchemaDescriptor interface {
 	Descriptor
 	SchemaDesc() *descpb.SchemaDescriptor
+	IsDDLLocked() bool
 }
 
 // TableDescriptor is an interface around the table descriptor types.
@@ -218,3 +219,22 @@ type Descriptors []Descriptor
 func (d Descriptors) Len() int           { return len(d) }
 func (d Descriptors) Less(i, j int) bool { return d[i].GetID() < d[j].GetID() }
 func (d Descriptors) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// PostDeserializationChanges are a set of booleans to indicate which types of
+// upgrades or fixes occurred when building a descriptor after
+// deserialization.
+type PostDeserializationChanges struct {
+	// DefaultPrivilegesAdded indicates that the descriptor had no privilege
+	// descriptor and was given a default one owned by the admin role.
+	DefaultPrivilegesAdded bool
+
+	// FixedPrivileges indicates that the privileges were fixed.
+	FixedPrivileges bool
+
+	// StateNormalized indicates that an unknown state was reset to PUBLIC.
+	StateNormalized bool
+
+	// OfflineReasonCleared indicates that the offline reason of a descriptor
+	// which is not offline was cleared.
+	OfflineReasonCleared bool
+}
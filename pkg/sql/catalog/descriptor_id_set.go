@@ -0,0 +1,62 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package catalog
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util"
+)
+
+// DescriptorIDSet efficiently stores an unordered set of descriptor ids.
+type DescriptorIDSet struct {
+	set util.FastIntSet
+}
+
+// MakeDescriptorIDSet returns a set initialized with the given values.
+func MakeDescriptorIDSet(ids ...descpb.ID) DescriptorIDSet {
+	s := DescriptorIDSet{}
+	for _, id := range ids {
+		s.Add(id)
+	}
+	return s
+}
+
+// Add adds an id to the set. No-op if the id is already in the set.
+func (d *DescriptorIDSet) Add(id descpb.ID) {
+	d.set.Add(int(id))
+}
+
+// Contains returns whether the set contains the given id.
+func (d DescriptorIDSet) Contains(id descpb.ID) bool {
+	return d.set.Contains(int(id))
+}
+
+// Len returns the number of the ids in the set.
+func (d DescriptorIDSet) Len() int {
+	return d.set.Len()
+}
+
+// ForEach calls f for each id in the set, in increasing order.
+func (d DescriptorIDSet) ForEach(f func(id descpb.ID)) {
+	d.set.ForEach(func(i int) { f(descpb.ID(i)) })
+}
+
+// Ordered returns a slice with all the ids in the set, in increasing order.
+func (d DescriptorIDSet) Ordered() []descpb.ID {
+	if d.Len() == 0 {
+		return nil
+	}
+	ret := make([]descpb.ID, 0, d.Len())
+	d.ForEach(func(id descpb.ID) {
+		ret = append(ret, id)
+	})
+	return ret
+}
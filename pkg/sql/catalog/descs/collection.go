@@ -387,6 +387,9 @@ func (tc *Collection) getMutableObjectDescriptor(
 	if err != nil || !foundSchema {
 		return nil, err
 	}
+	if err := schemadesc.CheckDDLAllowed(resolvedSchema.Desc); err != nil {
+		return nil, err
+	}
 
 	if refuseFurtherLookup, desc, err := tc.getUncommittedDescriptor(
 		dbID,
@@ -554,6 +557,9 @@ func (tc *Collection) ResolveSchema(
 		if err != nil || mutDesc == nil {
 			return false, catalog.ResolvedSchema{}, err
 		}
+		if err := schemadesc.CheckDDLAllowed(mutDesc); err != nil {
+			return false, catalog.ResolvedSchema{}, err
+		}
 		desc = mutDesc
 	} else {
 		immutDesc, err := tc.getUserDefinedSchemaVersion(ctx, txn, dbID, schemaName, flags)
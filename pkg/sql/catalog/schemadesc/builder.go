@@ -0,0 +1,111 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// SchemaDescriptorBuilder is a catalog.DescriptorBuilder for schema
+// descriptors, whose Build methods additionally have typed variants.
+type SchemaDescriptorBuilder interface {
+	catalog.DescriptorBuilder
+	BuildImmutableSchema() *Immutable
+	BuildExistingMutableSchema() *Mutable
+	BuildCreatedMutableSchema() *Mutable
+}
+
+type schemaDescriptorBuilder struct {
+	original      *descpb.SchemaDescriptor
+	maybeModified *descpb.SchemaDescriptor
+	changes       catalog.PostDeserializationChanges
+}
+
+var _ SchemaDescriptorBuilder = &schemaDescriptorBuilder{}
+
+// NewBuilder creates a new catalog.DescriptorBuilder object for building
+// schema descriptors. Like the constructors built on top of it, the builder
+// does not copy desc: immutable descriptors and created mutable descriptors
+// share state with it. Existing mutable descriptors own a copy.
+func NewBuilder(desc *descpb.SchemaDescriptor) SchemaDescriptorBuilder {
+	return &schemaDescriptorBuilder{original: desc}
+}
+
+// DescriptorType implements the catalog.DescriptorBuilder interface.
+func (sdb *schemaDescriptorBuilder) DescriptorType() catalog.DescriptorType {
+	return catalog.Schema
+}
+
+// RunPostDeserializationChanges implements the catalog.DescriptorBuilder
+// interface. It upgrades user defined schemas written by older versions, as
+// Mutable.RunPostDeserializationChanges does, and fixes their privileges as is
+// done for databases and tables. The changes made are recorded, and returned
+// by GetPostDeserializationChanges on the built descriptors. The public and
+// virtual schemas, whose IDs are reserved, are left alone.
+func (sdb *schemaDescriptorBuilder) RunPostDeserializationChanges(
+	_ context.Context, _ catalog.DescGetter,
+) error {
+	if descpb.IsReservedID(sdb.original.ID) {
+		return nil
+	}
+	sdb.maybeModified = protoutil.Clone(sdb.original).(*descpb.SchemaDescriptor)
+	sdb.changes = runPostDeserializationChanges(sdb.maybeModified)
+	return nil
+}
+
+// BuildImmutable implements the catalog.DescriptorBuilder interface.
+func (sdb *schemaDescriptorBuilder) BuildImmutable() catalog.Descriptor {
+	return sdb.BuildImmutableSchema()
+}
+
+// BuildImmutableSchema returns an Immutable.
+func (sdb *schemaDescriptorBuilder) BuildImmutableSchema() *Immutable {
+	return &Immutable{SchemaDescriptor: *sdb.latest(), postDeserializationChanges: sdb.changes}
+}
+
+// BuildExistingMutable implements the catalog.DescriptorBuilder interface.
+func (sdb *schemaDescriptorBuilder) BuildExistingMutable() catalog.MutableDescriptor {
+	return sdb.BuildExistingMutableSchema()
+}
+
+// BuildExistingMutableSchema returns a Mutable whose cluster version is the
+// descriptor as it was passed to NewBuilder.
+func (sdb *schemaDescriptorBuilder) BuildExistingMutableSchema() *Mutable {
+	return &Mutable{
+		Immutable: Immutable{
+			SchemaDescriptor:           *protoutil.Clone(sdb.latest()).(*descpb.SchemaDescriptor),
+			postDeserializationChanges: sdb.changes,
+		},
+		ClusterVersion: &Immutable{SchemaDescriptor: *sdb.original},
+	}
+}
+
+// BuildCreatedMutable implements the catalog.DescriptorBuilder interface.
+func (sdb *schemaDescriptorBuilder) BuildCreatedMutable() catalog.MutableDescriptor {
+	return sdb.BuildCreatedMutableSchema()
+}
+
+// BuildCreatedMutableSchema returns a Mutable without a cluster version.
+func (sdb *schemaDescriptorBuilder) BuildCreatedMutableSchema() *Mutable {
+	return &Mutable{Immutable: Immutable{SchemaDescriptor: *sdb.latest(), postDeserializationChanges: sdb.changes}}
+}
+
+// latest returns the descriptor including any post-deserialization changes.
+func (sdb *schemaDescriptorBuilder) latest() *descpb.SchemaDescriptor {
+	if sdb.maybeModified != nil {
+		return sdb.maybeModified
+	}
+	return sdb.original
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// ChecksumContribution returns a hash of the parts of the schema descriptor
+// which must agree between two nodes with a consistent view of the catalog:
+// its ID, parent, name, owner and state. The version and modification time
+// are deliberately excluded as they legitimately differ while a descriptor
+// change propagates.
+func (desc *Immutable) ChecksumContribution() uint64 {
+	h := fnv.New64a()
+	var buf [binary.MaxVarintLen64]byte
+	writeUint := func(v uint64) {
+		n := binary.PutUvarint(buf[:], v)
+		_, _ = h.Write(buf[:n])
+	}
+	// Strings are length-prefixed so that adjacent fields cannot be confused.
+	writeString := func(s string) {
+		writeUint(uint64(len(s)))
+		_, _ = h.Write([]byte(s))
+	}
+	writeUint(uint64(desc.ID))
+	writeUint(uint64(desc.ParentID))
+	writeString(desc.Name)
+	var owner string
+	if desc.Privileges != nil {
+		owner = desc.Privileges.Owner
+	}
+	writeString(owner)
+	writeUint(uint64(desc.State))
+	return h.Sum64()
+}
+
+// Fingerprint returns a hash of all the fields of the schema descriptor, so
+// that caches can detect any change to a descriptor. Like Equal, it does not
+// depend on the order of the draining names, nor on whether implied
+// privileges are spelled out. The hash only depends on the descriptor, so it
+// is stable across process restarts.
+func (desc *Immutable) Fingerprint() uint64 {
+	buf, err := protoutil.Marshal(desc.normalizedProto())
+	if err != nil {
+		panic(errors.NewAssertionErrorWithWrappedErrf(err,
+			"failed to marshal schema %q (%d)", desc.Name, errors.Safe(desc.ID)))
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(buf)
+	return h.Sum64()
+}
+
+// BackupFingerprint returns a hash of the content of the schema which is
+// recorded by BACKUP, that is of its BackupManifestEntry. The version and
+// modification time are excluded, so that an incremental backup can skip
+// schemas whose backed up content has not changed even if the descriptor was
+// rewritten in between.
+func (desc *Immutable) BackupFingerprint() uint64 {
+	entry := desc.BackupManifestEntry()
+	sc := entry.GetSchema()
+	sc.Version = 0
+	sc.ModificationTime = hlc.Timestamp{}
+	buf, err := protoutil.Marshal(sc)
+	if err != nil {
+		panic(errors.NewAssertionErrorWithWrappedErrf(err,
+			"failed to marshal schema %q (%d)", desc.Name, errors.Safe(desc.ID)))
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(buf)
+	return h.Sum64()
+}
+
+// CombineChecksumContributions folds the checksum contributions of the given
+// schemas into a single value. The schemas are folded in order of their IDs
+// so that the result does not depend on the order in which they were read.
+func CombineChecksumContributions(schemas []*Immutable) uint64 {
+	sorted := append([]*Immutable(nil), schemas...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, sc := range sorted {
+		binary.BigEndian.PutUint64(buf[:], sc.ChecksumContribution())
+		_, _ = h.Write(buf[:])
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+)
+
+// DiffSchemaSets compares the intended set of schemas with the actual one,
+// matching schemas by ID. It returns the IDs of the intended schemas which
+// are missing from actual, of the actual schemas which were not intended,
+// and of the schemas present in both under different names. Each list is
+// sorted by ID.
+func DiffSchemaSets(intended, actual []catalog.SchemaDescriptor) (missing, extra, renamed []descpb.ID) {
+	actualNames := make(map[descpb.ID]string, len(actual))
+	for _, sc := range actual {
+		actualNames[sc.GetID()] = sc.GetName()
+	}
+	intendedIDs := make(map[descpb.ID]struct{}, len(intended))
+	var missingIDs, extraIDs, renamedIDs descpb.IDs
+	for _, sc := range intended {
+		intendedIDs[sc.GetID()] = struct{}{}
+		name, ok := actualNames[sc.GetID()]
+		switch {
+		case !ok:
+			missingIDs = append(missingIDs, sc.GetID())
+		case name != sc.GetName():
+			renamedIDs = append(renamedIDs, sc.GetID())
+		}
+	}
+	for _, sc := range actual {
+		if _, ok := intendedIDs[sc.GetID()]; !ok {
+			extraIDs = append(extraIDs, sc.GetID())
+		}
+	}
+	sort.Sort(missingIDs)
+	sort.Sort(extraIDs)
+	sort.Sort(renamedIDs)
+	return missingIDs, extraIDs, renamedIDs
+}
+
+// Diff describes the changes from desc to other, which is expected to be
+// another version of the same schema. Each change is reported as a line of the
+// form "field: old -> new", or as an added or removed draining name. Changes
+// to the privileges other than the owner, and to the default privileges, are
+// only reported as such. All the fields compared by Equal are covered, so the
+// result is empty exactly when the descriptors are equal. If other is nil or is not backed by this package, a
+// single line saying so is returned.
+func (desc *Immutable) Diff(other catalog.SchemaDescriptor) []string {
+	o := asImmutable(other)
+	if o == nil {
+		return []string{fmt.Sprintf("other: not a schema descriptor (%T)", other)}
+	}
+	ret := []string{}
+	add := func(field string, from, to interface{}) {
+		ret = append(ret, fmt.Sprintf("%s: %v -> %v", field, from, to))
+	}
+	if desc.ID != o.ID {
+		add("id", desc.ID, o.ID)
+	}
+	if desc.Name != o.Name {
+		add("name", desc.Name, o.Name)
+	}
+	if desc.ParentID != o.ParentID {
+		add("parent id", desc.ParentID, o.ParentID)
+	}
+	if desc.Version != o.Version {
+		add("version", desc.Version, o.Version)
+	}
+	if desc.State != o.State {
+		add("state", desc.State, o.State)
+	}
+	if desc.OfflineReason != o.OfflineReason {
+		add("offline reason", fmt.Sprintf("%q", desc.OfflineReason), fmt.Sprintf("%q", o.OfflineReason))
+	}
+	if desc.ModificationTime != o.ModificationTime {
+		add("modification time", desc.ModificationTime, o.ModificationTime)
+	}
+	if desc.RegionAffinityEnumID != o.RegionAffinityEnumID {
+		add("region affinity enum id", desc.RegionAffinityEnumID, o.RegionAffinityEnumID)
+	}
+	if desc.ConvertedFromDatabaseID != o.ConvertedFromDatabaseID {
+		add("converted from database id", desc.ConvertedFromDatabaseID, o.ConvertedFromDatabaseID)
+	}
+	if desc.DisableAutoStats != o.DisableAutoStats {
+		add("disable auto stats", desc.DisableAutoStats, o.DisableAutoStats)
+	}
+	if from, to := commentString(desc.Comment), commentString(o.Comment); from != to {
+		add("comment", from, to)
+	}
+	if desc.DDLLocked != o.DDLLocked {
+		add("ddl locked", desc.DDLLocked, o.DDLLocked)
+	}
+	if desc.DefaultTableTTL != o.DefaultTableTTL {
+		add("default table ttl", desc.DefaultTableTTL, o.DefaultTableTTL)
+	}
+	from, to := *desc.GetPrivileges(), *o.GetPrivileges()
+	if from.Owner != to.Owner {
+		add("owner", from.Owner, to.Owner)
+		to.Owner = from.Owner
+	}
+	if !from.Equal(&to) {
+		ret = append(ret, "privileges: changed")
+	}
+	if !desc.GetDefaultPrivileges().Equal(o.GetDefaultPrivileges()) {
+		ret = append(ret, "default privileges: changed")
+	}
+	counts := make(map[descpb.NameInfo]int, len(desc.DrainingNames))
+	for _, n := range desc.DrainingNames {
+		counts[n]++
+	}
+	for _, n := range o.DrainingNames {
+		if counts[n] > 0 {
+			counts[n]--
+			continue
+		}
+		ret = append(ret, fmt.Sprintf("draining name added: %s", n.Name))
+	}
+	for _, n := range desc.DrainingNames {
+		if counts[n] > 0 {
+			counts[n]--
+			ret = append(ret, fmt.Sprintf("draining name removed: %s", n.Name))
+		}
+	}
+	return ret
+}
+
+// commentString formats a schema comment for Diff.
+func commentString(comment *string) string {
+	if comment == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("%q", *comment)
+}
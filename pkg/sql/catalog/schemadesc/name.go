@@ -0,0 +1,146 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/lex"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/errors"
+)
+
+// HasCaseInsensitiveCollision returns the first name in existing which is
+// equal to newName when compared case-insensitively, if any. Names are folded
+// with lex.NormalizeName so that the check agrees with name resolution.
+func HasCaseInsensitiveCollision(newName string, existing []string) (string, bool) {
+	normalized := lex.NormalizeName(newName)
+	for _, name := range existing {
+		if lex.NormalizeName(name) == normalized {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// NameRequiresQuoting returns whether the schema name must be wrapped in double
+// quotes to be used as an identifier in SQL output. This is the case if the
+// name is empty, is a reserved keyword, or contains upper case or special
+// characters. The rules are the ones used when formatting a tree.Name.
+func NameRequiresQuoting(name string) bool {
+	var buf bytes.Buffer
+	lex.EncodeRestrictedSQLIdent(&buf, name, lex.EncNoFlags)
+	return buf.String() != name
+}
+
+// ToSQLString returns the CREATE SCHEMA statement which creates the schema,
+// for use by SHOW CREATE SCHEMA. If the schema has an owner, the statement
+// includes an AUTHORIZATION clause. Identifiers are quoted as needed.
+func (desc *Immutable) ToSQLString() string {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE SCHEMA ")
+	lex.EncodeRestrictedSQLIdent(&buf, desc.Name, lex.EncNoFlags)
+	if desc.Privileges != nil && desc.Privileges.Owner != "" {
+		buf.WriteString(" AUTHORIZATION ")
+		lex.EncodeRestrictedSQLIdent(&buf, desc.Privileges.Owner, lex.EncNoFlags)
+	}
+	return buf.String()
+}
+
+// CanSwapNames checks that the names of the schemas a and b can be exchanged.
+// Both schemas must belong to the same database, and no other schema in that
+// database may currently hold, or still be draining, either of the two names.
+// The schemas in others which are a or b themselves, or which belong to a
+// different database, are ignored.
+func CanSwapNames(a, b *Mutable, others []catalog.SchemaDescriptor) error {
+	if a.ParentID != b.ParentID {
+		return errors.AssertionFailedf(
+			"cannot swap names of schemas %q (%d) and %q (%d) in different databases",
+			a.Name, errors.Safe(a.ID), b.Name, errors.Safe(b.ID))
+	}
+	for _, other := range others {
+		desc := other.SchemaDesc()
+		if desc.ID == a.ID || desc.ID == b.ID || desc.ParentID != a.ParentID {
+			continue
+		}
+		names := []string{desc.Name}
+		for _, drain := range desc.DrainingNames {
+			names = append(names, drain.Name)
+		}
+		for _, name := range names {
+			if name == a.Name || name == b.Name {
+				return pgerror.Newf(pgcode.DuplicateSchema,
+					"cannot swap names of schemas %q and %q: name %q is in use by schema %d",
+					a.Name, b.Name, name, errors.Safe(desc.ID))
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateRenameBatch checks that the schemas can all be renamed at once as
+// described by renames, which maps schema IDs to new names. Every renamed
+// schema must be among schemas and must be user defined. The new names must be valid, distinct from
+// each other and must neither be in use by any schema in schemas nor be
+// draining from one. As the old names of the renamed schemas become draining
+// names, a schema cannot take over the old name of another renamed schema
+// either, even though that name would be free once the batch is applied.
+func ValidateRenameBatch(renames map[descpb.ID]string, schemas []catalog.SchemaDescriptor) error {
+	byID := make(map[descpb.ID]catalog.SchemaDescriptor, len(schemas))
+	// taken maps every name currently held or being drained to the ID of the
+	// schema holding it.
+	taken := make(map[string]descpb.ID)
+	for _, sc := range schemas {
+		byID[sc.GetID()] = sc
+		taken[sc.GetName()] = sc.GetID()
+		for _, drain := range sc.GetDrainingNames() {
+			taken[drain.Name] = sc.GetID()
+		}
+	}
+
+	// Iterate in a deterministic order so that the error is deterministic too.
+	ids := make(descpb.IDs, 0, len(renames))
+	for id := range renames {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	newNames := make(map[string]descpb.ID, len(renames))
+	for _, id := range ids {
+		newName := renames[id]
+		sc, ok := byID[id]
+		if !ok {
+			return errors.AssertionFailedf("schema %d to be renamed to %q was not provided",
+				errors.Safe(id), newName)
+		}
+		if !NewImmutable(*sc.SchemaDesc()).CanBeRenamed() {
+			return pgerror.Newf(pgcode.InvalidSchemaName, "cannot rename schema %q", sc.GetName())
+		}
+		if err := IsSchemaNameValid(newName); err != nil {
+			return err
+		}
+		if other, ok := newNames[newName]; ok {
+			return pgerror.Newf(pgcode.DuplicateSchema,
+				"schemas %d and %d cannot both be renamed to %q",
+				errors.Safe(other), errors.Safe(id), newName)
+		}
+		newNames[newName] = id
+		if other, ok := taken[newName]; ok && (other != id || newName != sc.GetName()) {
+			return pgerror.Newf(pgcode.DuplicateSchema,
+				"cannot rename schema %q to %q: name is in use by schema %d",
+				sc.GetName(), newName, errors.Safe(other))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+)
+
+// namespaceOIDTypeTag is the type tag mixed into the hash of schema OIDs. It
+// must not change, as doing so would change the OIDs visible to clients.
+const namespaceOIDTypeTag uint8 = 1
+
+// NamespaceOID returns the OID of the schema with the given name in the given
+// database, as shown in pg_catalog.pg_namespace. Schema OIDs are derived by
+// hashing the database ID and the schema name.
+func NamespaceOID(dbID descpb.ID, name string) uint32 {
+	h := fnv.New32()
+	var buf [5]byte
+	buf[0] = namespaceOIDTypeTag
+	binary.BigEndian.PutUint32(buf[1:], uint32(dbID))
+	_, _ = h.Write(buf[:])
+	_, _ = h.Write([]byte(name))
+	return h.Sum32()
+}
+
+// OIDCollision returns whether the two distinct schemas a and b have the same
+// OID, which would result in duplicate rows in pg_catalog.pg_namespace.
+func OIDCollision(a, b catalog.SchemaDescriptor) bool {
+	if a.GetID() == b.GetID() {
+		return false
+	}
+	return NamespaceOID(a.GetParentID(), a.GetName()) == NamespaceOID(b.GetParentID(), b.GetName())
+}
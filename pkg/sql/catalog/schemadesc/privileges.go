@@ -0,0 +1,180 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"context"
+
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// GetPrivileges returns the privilege descriptor of the schema. It never
+// returns nil: if the descriptor has no privileges, a default privilege
+// descriptor owned by the admin role is synthesized, which is not retained.
+func (desc *Immutable) GetPrivileges() *descpb.PrivilegeDescriptor {
+	if desc.Privileges == nil {
+		return descpb.NewDefaultPrivilegeDescriptor(security.AdminRole)
+	}
+	return desc.Privileges
+}
+
+// GetOwner returns the owner of the schema. Descriptors created before owners
+// were introduced have no owner set, in which case they are owned by the admin
+// role.
+func (desc *Immutable) GetOwner() string {
+	if owner := desc.GetPrivileges().Owner; owner != "" {
+		return owner
+	}
+	return security.AdminRole
+}
+
+// SetOwner makes owner the owner of the schema.
+func (desc *Mutable) SetOwner(owner string) {
+	if desc.Privileges == nil {
+		desc.Privileges = descpb.NewDefaultPrivilegeDescriptor(owner)
+		return
+	}
+	desc.Privileges.SetOwner(owner)
+}
+
+// GetDefaultPrivileges returns the privileges granted by default on objects
+// created within the schema. It never returns nil: if none were set, an empty
+// descriptor is returned, which is not retained.
+func (desc *Immutable) GetDefaultPrivileges() *descpb.DefaultPrivilegeDescriptor {
+	if desc.DefaultPrivileges == nil {
+		return &descpb.DefaultPrivilegeDescriptor{}
+	}
+	return desc.DefaultPrivileges
+}
+
+// GrantDefaultPrivileges grants privList to user by default on the objects of
+// the given type created within the schema.
+func (desc *Mutable) GrantDefaultPrivileges(
+	objectType privilege.ObjectType, user string, privList privilege.List,
+) {
+	if desc.DefaultPrivileges == nil {
+		desc.DefaultPrivileges = &descpb.DefaultPrivilegeDescriptor{}
+	}
+	desc.DefaultPrivileges.Grant(objectType, user, privList)
+}
+
+// RevokeDefaultPrivileges revokes privList from user by default on the objects
+// of the given type created within the schema. The default privileges are
+// reset to nil once none remain.
+func (desc *Mutable) RevokeDefaultPrivileges(
+	objectType privilege.ObjectType, user string, privList privilege.List,
+) {
+	if desc.DefaultPrivileges == nil {
+		return
+	}
+	desc.DefaultPrivileges.Revoke(objectType, user, privList)
+	if len(desc.DefaultPrivileges.Objects) == 0 {
+		desc.DefaultPrivileges = nil
+	}
+}
+
+// ReconcilePublicSchemaPrivileges re-derives the privileges of a public
+// schema from those of its parent database, which it inherits. It returns
+// whether the schema's privileges were changed, in which case the caller
+// needs to write the schema descriptor. It is a no-op for any schema other
+// than the public schema.
+func ReconcilePublicSchemaPrivileges(schema *Mutable, db catalog.DatabaseDescriptor) (changed bool) {
+	if schema.GetSchemaKind() != catalog.SchemaPublic || db.GetPrivileges() == nil {
+		return false
+	}
+	if schema.Privileges != nil && schema.Privileges.Equal(db.GetPrivileges()) {
+		return false
+	}
+	schema.Privileges = protoutil.Clone(db.GetPrivileges()).(*descpb.PrivilegeDescriptor)
+	return true
+}
+
+// ReassignOwner makes to the owner of the schema if it is currently owned by
+// from, as done by REASSIGN OWNED BY from TO to. The owner's implicit ALL
+// privilege on the schema moves along with the ownership. It returns whether
+// the owner was changed, in which case the caller needs to write the schema
+// descriptor.
+func (desc *Mutable) ReassignOwner(from, to string) (changed bool) {
+	if desc.Privileges == nil || desc.Privileges.Owner != from || from == to {
+		return false
+	}
+	desc.Privileges.SetOwner(to)
+	return true
+}
+
+// CanCreateObjects returns whether user may create objects in the schema.
+// Admins may always do so, as may the owner and the holders of the CREATE
+// privilege, directly or through the public role, of either the schema or its
+// parent database db. The public schema has no privileges of its own and
+// defers to those of db alone. Role memberships are not expanded; callers
+// check each role of the user in turn.
+func (desc *Immutable) CanCreateObjects(
+	user string, db catalog.DatabaseDescriptor, isAdmin bool,
+) bool {
+	if isAdmin {
+		return true
+	}
+	if desc.GetSchemaKind() != catalog.SchemaPublic && canCreate(desc.Privileges, user) {
+		return true
+	}
+	return canCreate(db.GetPrivileges(), user)
+}
+
+// canCreate returns whether privs allow user to create objects.
+func canCreate(privs *descpb.PrivilegeDescriptor, user string) bool {
+	if privs == nil {
+		return false
+	}
+	return privs.Owner == user ||
+		privs.CheckPrivilege(user, privilege.CREATE) ||
+		privs.CheckPrivilege(security.PublicRole, privilege.CREATE)
+}
+
+// ValidateOwnerChange checks that currentUser may make newOwner the owner of
+// the schema, following the rules of Postgres: currentUser must own the
+// schema and must be a direct or indirect member of newOwner. Admins may
+// always do so. Ownership and membership through roles are expanded with
+// membership. Whether newOwner exists and has the CREATE privilege on the
+// parent database is checked by the caller.
+func (desc *Immutable) ValidateOwnerChange(
+	ctx context.Context, currentUser, newOwner string, membership catalog.RoleMembershipChecker,
+) error {
+	if currentUser == security.RootUser || currentUser == security.AdminRole {
+		return nil
+	}
+	memberOf, err := membership.MemberOfWithAdminOption(ctx, currentUser)
+	if err != nil {
+		return err
+	}
+	if _, ok := memberOf[security.AdminRole]; ok {
+		return nil
+	}
+	if owner := desc.GetOwner(); owner != currentUser {
+		if _, ok := memberOf[owner]; !ok {
+			return pgerror.Newf(pgcode.InsufficientPrivilege,
+				"must be owner of schema %s", tree.Name(desc.GetName()))
+		}
+	}
+	if newOwner != currentUser {
+		if _, ok := memberOf[newOwner]; !ok {
+			return pgerror.Newf(pgcode.InsufficientPrivilege,
+				"must be member of role %q", newOwner)
+		}
+	}
+	return nil
+}
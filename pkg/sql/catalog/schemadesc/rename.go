@@ -0,0 +1,58 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/errors"
+)
+
+// RenameWrites renames schema to newName and updates the schema mapping of
+// its parent database db accordingly: the old name is marked as dropped until
+// it is drained and the new name is added. The versions of both descriptors
+// are incremented. All checks are performed before either descriptor is
+// mutated, so that on error neither has changed.
+func RenameWrites(schema *Mutable, db *dbdesc.Mutable, newName string) error {
+	if schema.ParentID != db.ID {
+		return errors.AssertionFailedf("schema %q (%d) does not belong to database %q (%d)",
+			schema.Name, errors.Safe(schema.ID), db.Name, errors.Safe(db.ID))
+	}
+	if !schema.CanBeRenamed() {
+		return pgerror.Newf(pgcode.InvalidSchemaName, "cannot rename schema %q", schema.Name)
+	}
+	if err := IsSchemaNameValid(newName); err != nil {
+		return err
+	}
+	oldName := schema.Name
+	if info, ok := db.Schemas[oldName]; !ok || info.ID != schema.ID || info.Dropped {
+		return errors.AssertionFailedf(
+			"old name %q not present in database schema mapping", oldName)
+	}
+	if info, ok := db.Schemas[newName]; ok {
+		if info.Dropped {
+			return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+				"schema name %q is still being released", newName)
+		}
+		return pgerror.Newf(pgcode.DuplicateSchema, "schema %q already exists", newName)
+	}
+
+	if err := schema.SetName(newName); err != nil {
+		return err
+	}
+	schema.MaybeIncrementVersion()
+	db.Schemas[oldName] = descpb.DatabaseDescriptor_SchemaInfo{ID: schema.ID, Dropped: true}
+	db.Schemas[newName] = descpb.DatabaseDescriptor_SchemaInfo{ID: schema.ID}
+	db.MaybeIncrementVersion()
+	return nil
+}
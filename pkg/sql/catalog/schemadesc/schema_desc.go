@@ -11,17 +11,31 @@
 package schemadesc
 
 import (
+	"context"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unsafe"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/lex"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uint128"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
 )
 
 var _ catalog.SchemaDescriptor = (*Immutable)(nil)
@@ -31,6 +45,25 @@ var _ catalog.MutableDescriptor = (*Mutable)(nil)
 // Immutable wraps a Schema descriptor and provides methods on it.
 type Immutable struct {
 	descpb.SchemaDescriptor
+
+	// temporary is set for the session-scoped pg_temp_<sessionID> schemas.
+	// Temporary schemas are only recorded in the namespace table, never as
+	// descriptors, so this is not persisted: descriptors for them are
+	// synthesized with NewTemporarySchema.
+	temporary bool
+
+	// virtual is set for the virtual schemas, such as pg_catalog, made by
+	// NewVirtualSchema. Virtual schemas live in no storage at all, so their
+	// descriptors must never be persisted.
+	virtual bool
+
+	// postDeserializationChanges records the changes made to the descriptor by
+	// the builder's RunPostDeserializationChanges.
+	postDeserializationChanges catalog.PostDeserializationChanges
+
+	// descProto caches the *descpb.Descriptor returned by DescriptorProto. It
+	// is accessed atomically.
+	descProto unsafe.Pointer
 }
 
 // Mutable is a mutable reference to a SchemaDescriptor.
@@ -49,22 +82,87 @@ type Mutable struct {
 
 // NewMutableExisting returns a Mutable from the
 // given schema descriptor with the cluster version also set to the descriptor.
-// This is for schemas that already exist.
+// This is for schemas that already exist. A descriptor without privileges is
+// given the default privileges, owned by the admin role.
 func NewMutableExisting(desc descpb.SchemaDescriptor) *Mutable {
+	maybeDefaultPrivileges(&desc)
+	return NewBuilder(&desc).BuildExistingMutableSchema()
+}
+
+// maybeDefaultPrivileges gives desc the default privileges, owned by the admin
+// role, if it has none. Privileges which are already present are kept.
+func maybeDefaultPrivileges(desc *descpb.SchemaDescriptor) {
+	if desc.Privileges == nil {
+		desc.Privileges = descpb.NewDefaultPrivilegeDescriptor(security.AdminRole)
+	}
+}
+
+// NewMutableFromImmutable returns a Mutable working copy of imm, with the
+// cluster version set to imm. The descriptor is deep copied, so changes to the
+// Mutable do not affect imm. It panics if imm is a virtual schema, which
+// cannot be modified.
+func NewMutableFromImmutable(imm *Immutable) *Mutable {
+	if imm.virtual {
+		panic(errors.AssertionFailedf("virtual schema %q (%d) cannot be modified",
+			imm.Name, errors.Safe(imm.ID)))
+	}
 	return &Mutable{
-		Immutable:      makeImmutable(*protoutil.Clone(&desc).(*descpb.SchemaDescriptor)),
-		ClusterVersion: NewImmutable(desc),
+		Immutable: Immutable{
+			SchemaDescriptor: *protoutil.Clone(&imm.SchemaDescriptor).(*descpb.SchemaDescriptor),
+			temporary:        imm.temporary,
+		},
+		ClusterVersion: imm,
 	}
 }
 
 // NewImmutable makes a new Schema descriptor.
 func NewImmutable(desc descpb.SchemaDescriptor) *Immutable {
-	m := makeImmutable(desc)
-	return &m
+	return NewBuilder(&desc).BuildImmutableSchema()
+}
+
+// NewImmutableSlice makes Schema descriptors for each of descs, in order, as
+// NewImmutable does. The Immutable structs share a single allocation. Unlike
+// NewImmutable, each descriptor is deep copied so that the results do not
+// alias descs.
+func NewImmutableSlice(descs []descpb.SchemaDescriptor) []catalog.SchemaDescriptor {
+	backing := make([]Immutable, len(descs))
+	ret := make([]catalog.SchemaDescriptor, len(descs))
+	for i := range descs {
+		desc := protoutil.Clone(&descs[i]).(*descpb.SchemaDescriptor)
+		backing[i] = *NewBuilder(desc).BuildImmutableSchema()
+		ret[i] = &backing[i]
+	}
+	return ret
 }
 
-func makeImmutable(desc descpb.SchemaDescriptor) Immutable {
-	return Immutable{SchemaDescriptor: desc}
+// NewTemporarySchema makes a new descriptor for the temporary schema with the
+// given name and ID in the parentDB database. Only descriptors made this way
+// are treated as temporary schemas; the name is expected to be of the form
+// pg_temp_<sessionID>, which is otherwise reserved.
+func NewTemporarySchema(name string, id descpb.ID, parentDB descpb.ID) *Immutable {
+	desc := NewImmutable(descpb.SchemaDescriptor{
+		Name:     name,
+		ID:       id,
+		ParentID: parentDB,
+		Version:  1,
+	})
+	desc.temporary = true
+	return desc
+}
+
+// NewVirtualSchema makes a new descriptor for the virtual schema with the given
+// name and well-known ID in the parentDB database. The descriptor exists only
+// to let virtual schemas be handled like other schemas: it cannot be converted
+// into a descriptor proto or a Mutable, so it cannot be persisted.
+func NewVirtualSchema(name string, id descpb.ID, parentDB descpb.ID) *Immutable {
+	desc := NewImmutable(descpb.SchemaDescriptor{
+		Name:     name,
+		ID:       id,
+		ParentID: parentDB,
+		Version:  1,
+	})
+	desc.virtual = true
+	return desc
 }
 
 // Reference these functions to defeat the linter.
@@ -72,13 +170,40 @@ var (
 	_ = NewImmutable
 )
 
+// MakeDefaultPublicSchemaForDatabase returns the descriptor of the public
+// schema of a newly created database. The public schema is given the freshly
+// allocated id and inherits the privileges of the database. It panics if the
+// resulting descriptor does not pass validation, which indicates that the
+// database descriptor is malformed.
+func MakeDefaultPublicSchemaForDatabase(
+	db *descpb.DatabaseDescriptor, id descpb.ID,
+) descpb.SchemaDescriptor {
+	desc := descpb.SchemaDescriptor{
+		Name:     sessiondata.PublicSchemaName,
+		ID:       id,
+		ParentID: db.ID,
+		Version:  1,
+	}
+	if db.Privileges != nil {
+		desc.Privileges = protoutil.Clone(db.Privileges).(*descpb.PrivilegeDescriptor)
+	}
+	var errs catalog.ValidationErrors
+	NewImmutable(desc).ValidateSelf(&errs)
+	if err := errs.CombinedError(); err != nil {
+		panic(errors.NewAssertionErrorWithWrappedErrf(err,
+			"invalid public schema for database %q (%d)", db.Name, errors.Safe(db.ID)))
+	}
+	return desc
+}
+
 // NewMutableCreatedSchemaDescriptor returns a Mutable from the
 // given SchemaDescriptor with the cluster version being the zero schema. This
-// is for a schema that is created within the current transaction.
+// is for a schema that is created within the current transaction. As with
+// NewMutableExisting, a descriptor without privileges is given the default
+// privileges.
 func NewMutableCreatedSchemaDescriptor(desc descpb.SchemaDescriptor) *Mutable {
-	return &Mutable{
-		Immutable: makeImmutable(desc),
-	}
+	maybeDefaultPrivileges(&desc)
+	return NewBuilder(&desc).BuildCreatedMutableSchema()
 }
 
 // SetDrainingNames implements the MutableDescriptor interface.
@@ -101,6 +226,11 @@ func (desc *Immutable) TypeName() string {
 	return "schema"
 }
 
+// DescriptorType returns the type of the descriptor, catalog.Schema.
+func (desc *Immutable) DescriptorType() catalog.DescriptorType {
+	return catalog.Schema
+}
+
 // SchemaDesc implements the Descriptor interface.
 func (desc *Immutable) SchemaDesc() *descpb.SchemaDescriptor {
 	return &desc.SchemaDescriptor
@@ -108,41 +238,450 @@ func (desc *Immutable) SchemaDesc() *descpb.SchemaDescriptor {
 
 // Adding implements the Descriptor interface.
 func (desc *Immutable) Adding() bool {
-	return false
+	return desc.State == descpb.SchemaDescriptor_ADD
 }
 
-// Offline implements the Descriptor interface.
+// Offline implements the Descriptor interface. A schema which is being dropped
+// is offline as well.
 func (desc *Immutable) Offline() bool {
-	return false
+	return desc.State == descpb.SchemaDescriptor_OFFLINE || desc.State == descpb.SchemaDescriptor_DROP
 }
 
 // GetOfflineReason implements the Descriptor interface.
 func (desc *Immutable) GetOfflineReason() string {
-	return ""
+	return desc.OfflineReason
+}
+
+// SetOffline takes the schema offline for the given reason, e.g. while its
+// objects are being restored. Name resolution skips offline schemas.
+func (desc *Mutable) SetOffline(reason string) {
+	desc.State = descpb.SchemaDescriptor_OFFLINE
+	desc.OfflineReason = reason
+}
+
+// SetPublic makes the schema public, bringing it back online.
+func (desc *Mutable) SetPublic() {
+	desc.State = descpb.SchemaDescriptor_PUBLIC
+	desc.OfflineReason = ""
+}
+
+// GetPostDeserializationChanges returns the set of changes which occurred to
+// this descriptor post deserialization.
+func (desc *Immutable) GetPostDeserializationChanges() catalog.PostDeserializationChanges {
+	return desc.postDeserializationChanges
+}
+
+// RunPostDeserializationChanges upgrades a schema descriptor written by an
+// older version: a missing privilege descriptor is replaced by a default one
+// owned by the admin role, invalid privileges are fixed, an unknown state is
+// reset to PUBLIC and an offline reason is cleared unless the schema is
+// offline. It returns the changes which were made, which are also recorded
+// and returned by GetPostDeserializationChanges. If any were made, the caller
+// needs to write the descriptor.
+func (desc *Mutable) RunPostDeserializationChanges() catalog.PostDeserializationChanges {
+	changes := runPostDeserializationChanges(&desc.SchemaDescriptor)
+	recorded := &desc.postDeserializationChanges
+	recorded.DefaultPrivilegesAdded = recorded.DefaultPrivilegesAdded || changes.DefaultPrivilegesAdded
+	recorded.FixedPrivileges = recorded.FixedPrivileges || changes.FixedPrivileges
+	recorded.StateNormalized = recorded.StateNormalized || changes.StateNormalized
+	recorded.OfflineReasonCleared = recorded.OfflineReasonCleared || changes.OfflineReasonCleared
+	return changes
+}
+
+// runPostDeserializationChanges upgrades desc as described for
+// RunPostDeserializationChanges, and returns the changes which were made.
+func runPostDeserializationChanges(
+	desc *descpb.SchemaDescriptor,
+) (changes catalog.PostDeserializationChanges) {
+	if desc.Privileges == nil {
+		desc.Privileges = descpb.NewDefaultPrivilegeDescriptor(security.AdminRole)
+		changes.DefaultPrivilegesAdded = true
+	} else {
+		changes.FixedPrivileges = descpb.MaybeFixPrivileges(desc.ID, desc.Privileges)
+	}
+	if _, ok := descpb.SchemaDescriptor_State_name[int32(desc.State)]; !ok {
+		desc.State = descpb.SchemaDescriptor_PUBLIC
+		changes.StateNormalized = true
+	}
+	if desc.State != descpb.SchemaDescriptor_OFFLINE && desc.OfflineReason != "" {
+		desc.OfflineReason = ""
+		changes.OfflineReasonCleared = true
+	}
+	return changes
 }
 
-// DescriptorProto wraps a SchemaDescriptor in a Descriptor.
+// DescriptorProto wraps a SchemaDescriptor in a Descriptor. The wrapper is
+// built on first use and reused afterwards. It points at the storage of the
+// descriptor, so a cached wrapper is discarded if the Immutable was copied.
+// It panics for virtual schemas, which must not be persisted.
 func (desc *Immutable) DescriptorProto() *descpb.Descriptor {
+	if desc.virtual {
+		panic(errors.AssertionFailedf("virtual schema %q (%d) cannot be persisted",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+	if cached := (*descpb.Descriptor)(atomic.LoadPointer(&desc.descProto)); cached != nil &&
+		cached.GetSchema() == &desc.SchemaDescriptor {
+		return cached
+	}
+	ret := newDescriptorProto(&desc.SchemaDescriptor)
+	atomic.StorePointer(&desc.descProto, unsafe.Pointer(ret))
+	return ret
+}
+
+// DescriptorProto wraps a SchemaDescriptor in a Descriptor. Unlike for
+// Immutable, a new wrapper is returned on every call.
+func (desc *Mutable) DescriptorProto() *descpb.Descriptor {
+	return newDescriptorProto(&desc.SchemaDescriptor)
+}
+
+func newDescriptorProto(desc *descpb.SchemaDescriptor) *descpb.Descriptor {
 	return &descpb.Descriptor{
 		Union: &descpb.Descriptor_Schema{
-			Schema: &desc.SchemaDescriptor,
+			Schema: desc,
 		},
 	}
 }
 
+// WithoutDrainingNames returns a deep copy of the descriptor without any
+// draining names. It is useful for comparing the stable content of
+// descriptors independently of in-flight renames.
+func (desc *Immutable) WithoutDrainingNames() *Immutable {
+	clone := protoutil.Clone(&desc.SchemaDescriptor).(*descpb.SchemaDescriptor)
+	clone.DrainingNames = nil
+	ret := NewImmutable(*clone)
+	ret.temporary = desc.temporary
+	ret.virtual = desc.virtual
+	return ret
+}
+
+// GetDrainingNames implements the Descriptor interface. It returns a copy of
+// the draining names, so that callers cannot modify a descriptor which may be
+// shared, for instance through a lease. Mutable callers which need to change
+// the draining names should use SetName, SetDrainingNames or
+// RemoveDrainingNames instead.
+func (desc *Immutable) GetDrainingNames() []descpb.NameInfo {
+	if len(desc.DrainingNames) == 0 {
+		return nil
+	}
+	return append([]descpb.NameInfo(nil), desc.DrainingNames...)
+}
+
+// HasDrainingNames returns whether the schema has any draining names.
+func (desc *Immutable) HasDrainingNames() bool {
+	return len(desc.DrainingNames) > 0
+}
+
+// ForEachDrainingName calls fn on each draining name of the schema, in the
+// order in which they were added. Iteration stops at the first error returned
+// by fn, which is returned. The names are passed by value: fn cannot modify
+// the draining names of the descriptor.
+func (desc *Immutable) ForEachDrainingName(fn func(descpb.NameInfo) error) error {
+	for _, drain := range desc.DrainingNames {
+		if err := fn(drain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackupManifestEntry returns the descriptor to record for the schema in a
+// backup manifest. It carries the identity, owner and privileges of the
+// schema, along with the rest of its persisted state, but not its draining
+// names: those are transient and a restored schema must not inherit them.
+func (desc *Immutable) BackupManifestEntry() descpb.Descriptor {
+	return *desc.WithoutDrainingNames().DescriptorProto()
+}
+
+// RegionEnumID returns the ID of the multi-region enum of the parent database
+// which the schema has an affinity to. If the schema has no region affinity,
+// (InvalidID, false) is returned.
+func (desc *Immutable) RegionEnumID() (descpb.ID, bool) {
+	if desc.RegionAffinityEnumID == descpb.InvalidID {
+		return descpb.InvalidID, false
+	}
+	return desc.RegionAffinityEnumID, true
+}
+
+// GetReferencedDescIDs returns the IDs of the descriptors the schema depends
+// on: its parent database and, if it has a region affinity, the multi-region
+// enum of the database. Objects within the schema are not included, as the
+// schema descriptor does not record them.
+func (desc *Immutable) GetReferencedDescIDs() (catalog.DescriptorIDSet, error) {
+	ids := catalog.MakeDescriptorIDSet(desc.ParentID)
+	if enumID, ok := desc.RegionEnumID(); ok {
+		ids.Add(enumID)
+	}
+	return ids, nil
+}
+
+// AutoStatsDisabled returns whether automatic statistics collection is
+// disabled for the tables in the schema.
+func (desc *Immutable) AutoStatsDisabled() bool {
+	return desc.DisableAutoStats
+}
+
+// IsDDLLocked returns whether DDL on the schema and the objects in it is
+// currently rejected.
+func (desc *Immutable) IsDDLLocked() bool {
+	return desc.DDLLocked
+}
+
+// SetDDLLocked locks or unlocks the schema against DDL.
+func (desc *Mutable) SetDDLLocked(locked bool) {
+	desc.DDLLocked = locked
+}
+
+// CheckDDLAllowed returns an error if DDL on the schema or the objects in it
+// is currently rejected. It is called by the descriptor collection whenever
+// the schema, or an object in it, is resolved by name for modification, so
+// that it covers every DDL statement.
+func CheckDDLAllowed(desc catalog.SchemaDescriptor) error {
+	if desc == nil || !desc.IsDDLLocked() {
+		return nil
+	}
+	return pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+		"schema %q is locked for maintenance", desc.GetName())
+}
+
+// SortKey returns a key by which schemas can be ordered deterministically when
+// listing them. The public schema sorts first, followed by the virtual schemas
+// and then all other schemas by their normalized name. The original name is
+// used as a tie-breaker between names which only differ by case.
+func (desc *Immutable) SortKey() string {
+	var rank string
+	switch desc.GetSchemaKind() {
+	case catalog.SchemaPublic:
+		rank = "0"
+	case catalog.SchemaVirtual:
+		rank = "1"
+	default:
+		rank = "2"
+	}
+	return rank + lex.NormalizeName(desc.Name) + "\x00" + desc.Name
+}
+
+// HasConcurrentSchemaChange returns whether the schema is undergoing a schema
+// change, which is the case while it has draining names or is not public.
+func (desc *Immutable) HasConcurrentSchemaChange() bool {
+	return len(desc.DrainingNames) > 0 || desc.State != descpb.SchemaDescriptor_PUBLIC
+}
+
+// AllowedLeaseVersions returns the versions of the schema descriptor which
+// may be leased under the two-version invariant. The current version may
+// always be leased. The prior version may additionally be leased for as long
+// as the change which produced the current version is still in flight, which
+// for schemas means while the schema has draining names or is not public:
+// nodes may hold leases on the prior version under the old name until the
+// draining names are removed. Once they are, all leases on the prior version
+// have been released and only the current version can be leased.
+func (desc *Immutable) AllowedLeaseVersions() (
+	current, prior descpb.DescriptorVersion, hasPrior bool,
+) {
+	current = desc.Version
+	if current > 1 && desc.HasConcurrentSchemaChange() {
+		return current, current - 1, true
+	}
+	return current, 0, false
+}
+
+// SafeForFollowerRead returns whether the schema may be resolved using a
+// follower read. A stale read could miss the effects of an in-flight schema
+// change on the schema, in which case a leaseholder read must be used.
+func (desc *Immutable) SafeForFollowerRead() bool {
+	return !desc.HasConcurrentSchemaChange()
+}
+
+// DropLeaseWaitDeadline returns the timestamp after which no node can hold a
+// lease on a version of the dropped schema which predates the drop, at which
+// point the descriptor can be deleted. modTime is the modification time of the
+// version which dropped the schema, and leaseDuration is the longest duration
+// for which a lease may be granted, including jitter.
+func (desc *Immutable) DropLeaseWaitDeadline(
+	modTime hlc.Timestamp, leaseDuration time.Duration,
+) hlc.Timestamp {
+	return modTime.Add(leaseDuration.Nanoseconds(), 0)
+}
+
+// AllowsTypes returns whether user defined types may be created in the
+// schema. Virtual schemas never hold types. Temporary schemas do not either,
+// unless enabled for testing with TestingAllowTypesInTemporarySchemas.
+func (desc *Immutable) AllowsTypes() bool {
+	switch desc.GetSchemaKind() {
+	case catalog.SchemaVirtual:
+		return false
+	case catalog.SchemaTemporary:
+		return testAllowTypesInTemporarySchemas
+	default:
+		return true
+	}
+}
+
+var testAllowTypesInTemporarySchemas bool
+
+// TestingAllowTypesInTemporarySchemas makes AllowsTypes return true for
+// temporary schemas and returns a function which restores the default.
+func TestingAllowTypesInTemporarySchemas() func() {
+	testAllowTypesInTemporarySchemas = true
+	return func() {
+		testAllowTypesInTemporarySchemas = false
+	}
+}
+
+// MeteringWeight returns the number of descriptors the schema counts as for
+// tenant resource metering. Virtual schemas and the public schema, which are
+// synthesized rather than stored as descriptors, count as zero.
+func (desc *Immutable) MeteringWeight() int64 {
+	if desc.ID == keys.PublicSchemaID || desc.GetSchemaKind() == catalog.SchemaVirtual {
+		return 0
+	}
+	return 1
+}
+
+// TelemetryCounters returns the contribution of the schema to the schema
+// telemetry report, keyed by counter name. The report sums the counters of all
+// schemas.
+func (desc *Immutable) TelemetryCounters() map[string]int {
+	var counter string
+	switch desc.GetSchemaKind() {
+	case catalog.SchemaPublic:
+		counter = "public_schema"
+	case catalog.SchemaVirtual:
+		counter = "virtual_schema"
+	case catalog.SchemaTemporary:
+		counter = "temp_schema"
+	default:
+		counter = "user_schema"
+	}
+	return map[string]int{counter: 1}
+}
+
+// DebugSummary returns a one-line summary of the schema descriptor for
+// inclusion in debug zips. Use redact.Sprint on the descriptor instead where
+// the name and owner need to be redacted.
+func (desc *Immutable) DebugSummary() string {
+	return desc.String()
+}
+
+// String implements the fmt.Stringer interface. It shadows the String method
+// of the embedded proto so that formatting the descriptor goes through
+// SafeFormat.
+func (desc *Immutable) String() string {
+	return redact.StringWithoutMarkers(desc)
+}
+
+// SafeFormat implements the redact.SafeFormatter interface. IDs, versions and
+// the state are safe; the name and the owner are redactable.
+func (desc *Immutable) SafeFormat(w redact.SafePrinter, _ rune) {
+	var kind string
+	switch desc.GetSchemaKind() {
+	case catalog.SchemaPublic:
+		kind = "public"
+	case catalog.SchemaVirtual:
+		kind = "virtual"
+	case catalog.SchemaTemporary:
+		kind = "temporary"
+	default:
+		kind = "user-defined"
+	}
+	var owner string
+	if desc.Privileges != nil {
+		owner = desc.Privileges.Owner
+	}
+	w.Printf("%s schema %d.%s (%d): owner=%s state=%s version=%d draining_names=%d",
+		redact.Safe(kind), redact.Safe(desc.ParentID), desc.Name, redact.Safe(desc.ID),
+		owner, redact.Safe(desc.State), redact.Safe(desc.Version),
+		redact.Safe(len(desc.DrainingNames)))
+}
+
+// Equal returns whether other describes the same schema as desc, that is
+// whether all the fields of their descriptors are equal. The order of the
+// draining names is not significant, and a descriptor without privileges or
+// default privileges is equal to one with the privileges it implies. It
+// returns false if other is nil or is not backed by this package.
+func (desc *Immutable) Equal(other catalog.SchemaDescriptor) bool {
+	o := asImmutable(other)
+	if o == nil {
+		return false
+	}
+	return desc.normalizedProto().Equal(o.normalizedProto())
+}
+
+// normalizedProto returns a copy of the descriptor with its draining names
+// sorted and its privileges and default privileges filled in, so that
+// descriptors which only differ in those respects compare equal.
+func (desc *Immutable) normalizedProto() *descpb.SchemaDescriptor {
+	sc := protoutil.Clone(&desc.SchemaDescriptor).(*descpb.SchemaDescriptor)
+	sc.Privileges = desc.GetPrivileges()
+	sc.DefaultPrivileges = desc.GetDefaultPrivileges()
+	sort.Slice(sc.DrainingNames, func(i, j int) bool {
+		a, b := sc.DrainingNames[i], sc.DrainingNames[j]
+		if a.ParentID != b.ParentID {
+			return a.ParentID < b.ParentID
+		}
+		if a.ParentSchemaID != b.ParentSchemaID {
+			return a.ParentSchemaID < b.ParentSchemaID
+		}
+		return a.Name < b.Name
+	})
+	return sc
+}
+
+// asImmutable returns the Immutable backing sc, or nil if sc is nil or is not
+// backed by this package.
+func asImmutable(sc catalog.SchemaDescriptor) *Immutable {
+	switch t := sc.(type) {
+	case *Immutable:
+		return t
+	case *Mutable:
+		if t != nil {
+			return &t.Immutable
+		}
+	}
+	return nil
+}
+
 // NameResolutionResult implements the ObjectDescriptor interface.
 func (desc *Immutable) NameResolutionResult() {}
 
-// MaybeIncrementVersion implements the MutableDescriptor interface.
+// GetObjectType returns the type of object the schema is when returned as a
+// name resolution result, which is its TypeName.
+func (desc *Immutable) GetObjectType() string {
+	return desc.TypeName()
+}
+
+// GetObjectName returns the name of the schema when returned as a name
+// resolution result. Schemas are named within their database, so this is the
+// unqualified schema name.
+func (desc *Immutable) GetObjectName() string {
+	return desc.Name
+}
+
+// MaybeIncrementVersion implements the MutableDescriptor interface. The version
+// is incremented to one past the cluster version unless it is already ahead of
+// it, by however much. Newly created descriptors have no cluster version and
+// keep their initial version. In both cases the modification time is left to
+// be filled in when the descriptor is committed.
 func (desc *Mutable) MaybeIncrementVersion() {
+	if desc.ClusterVersion == nil {
+		desc.ModificationTime = hlc.Timestamp{}
+		return
+	}
 	// Already incremented, no-op.
-	if desc.ClusterVersion == nil || desc.Version == desc.ClusterVersion.Version+1 {
+	if desc.Version > desc.ClusterVersion.Version {
 		return
 	}
-	desc.Version++
+	desc.Version = desc.ClusterVersion.Version + 1
 	desc.ModificationTime = hlc.Timestamp{}
 }
 
+// HasPendingModificationTime returns whether the modification time of the
+// schema is still unset, to be filled in when the descriptor is committed.
+// This is the case after MaybeIncrementVersion; a descriptor read from
+// storage always has a modification time.
+func (desc *Immutable) HasPendingModificationTime() bool {
+	return desc.ModificationTime.IsEmpty()
+}
+
 // OriginalName implements the MutableDescriptor interface.
 func (desc *Mutable) OriginalName() string {
 	if desc.ClusterVersion == nil {
@@ -167,11 +706,21 @@ func (desc *Mutable) OriginalVersion() descpb.DescriptorVersion {
 	return desc.ClusterVersion.Version
 }
 
+// IsUncommittedVersion returns whether the version of the schema is ahead of
+// its cluster version, that is whether it has changes which have not been
+// written yet. A newly created schema has no cluster version and is always
+// uncommitted.
+func (desc *Mutable) IsUncommittedVersion() bool {
+	return desc.Version > desc.OriginalVersion()
+}
+
 // ImmutableCopy implements the MutableDescriptor interface.
 func (desc *Mutable) ImmutableCopy() catalog.Descriptor {
 	// TODO (lucy): Should the immutable descriptor constructors always make a
 	// copy, so we don't have to do it here?
-	return NewImmutable(*protoutil.Clone(desc.SchemaDesc()).(*descpb.SchemaDescriptor))
+	ret := NewImmutable(*protoutil.Clone(desc.SchemaDesc()).(*descpb.SchemaDescriptor))
+	ret.temporary = desc.temporary
+	return ret
 }
 
 // IsNew implements the MutableDescriptor interface.
@@ -179,25 +728,450 @@ func (desc *Mutable) IsNew() bool {
 	return desc.ClusterVersion == nil
 }
 
-// SetName sets the name of the schema. It handles installing a draining name
-// for the old name of the descriptor.
-func (desc *Mutable) SetName(name string) {
-	desc.DrainingNames = append(desc.DrainingNames, descpb.NameInfo{
+// RemoveStaleDrainingNames removes the draining names of the schema whose
+// system.namespace entry no longer exists or no longer refers to the schema,
+// for instance because the GC of the names was interrupted after deleting the
+// entries. It returns the number of draining names removed.
+func (desc *Mutable) RemoveStaleDrainingNames(
+	ctx context.Context, vdg catalog.ValidationDescGetter,
+) (removed int, err error) {
+	type scope struct{ parentID, parentSchemaID descpb.ID }
+	entries := make(map[scope]map[string]descpb.ID)
+	var live []descpb.NameInfo
+	for _, drain := range desc.DrainingNames {
+		s := scope{drain.ParentID, drain.ParentSchemaID}
+		if _, ok := entries[s]; !ok {
+			if entries[s], err = vdg.GetNamespaceEntries(ctx, s.parentID, s.parentSchemaID); err != nil {
+				return 0, err
+			}
+		}
+		if id, ok := entries[s][drain.Name]; ok && id == desc.ID {
+			live = append(live, drain)
+		} else {
+			removed++
+		}
+	}
+	if removed > 0 {
+		desc.DrainingNames = live
+	}
+	return removed, nil
+}
+
+// RemoveDrainingNames removes the given names from the draining names of the
+// schema, once the schema change GC has deleted their system.namespace
+// entries. Names which are not draining are ignored. It returns the number of
+// draining names removed. A new slice is allocated so that the draining names
+// of the cluster version are left untouched.
+func (desc *Mutable) RemoveDrainingNames(toRemove []descpb.NameInfo) (removed int) {
+	if len(toRemove) == 0 {
+		return 0
+	}
+	remove := make(map[descpb.NameInfo]struct{}, len(toRemove))
+	for _, ni := range toRemove {
+		remove[ni] = struct{}{}
+	}
+	var live []descpb.NameInfo
+	for _, drain := range desc.DrainingNames {
+		if _, ok := remove[drain]; ok {
+			removed++
+		} else {
+			live = append(live, drain)
+		}
+	}
+	if removed > 0 {
+		desc.DrainingNames = live
+	}
+	return removed
+}
+
+// EligibleForFastDrop returns whether the schema was created in the current
+// transaction, in which case a DROP SCHEMA can delete the descriptor and its
+// namespace entries inline instead of scheduling a GC job. Schema descriptors
+// do not record the objects they contain, so the caller must still check that
+// the schema is empty with ValidateDropPreconditions.
+func (desc *Mutable) EligibleForFastDrop() bool {
+	return desc.IsNew() && !desc.Dropped() && desc.GetSchemaKind() == catalog.SchemaUserDefined
+}
+
+// DescriptorKey returns the key of the schema's row in system.descriptor
+// under the given codec.
+func (desc *Immutable) DescriptorKey(codec keys.SQLCodec) roachpb.Key {
+	return codec.DescMetadataKey(uint32(desc.ID))
+}
+
+// CanBeRenamed returns whether the schema may be renamed. Only user defined
+// schemas can be renamed; the public, virtual and temporary schemas cannot.
+func (desc *Immutable) CanBeRenamed() bool {
+	return desc.GetSchemaKind() == catalog.SchemaUserDefined
+}
+
+// The system.namespace index and column family holding the descriptor ID of a
+// name, as laid out by systemschema.NamespaceTable. They are spelled out here
+// because that package, like catalogkeys, depends on this one.
+const (
+	namespaceTablePrimaryIndexID = 1
+	namespaceTableIDFamilyID     = 4
+)
+
+// AllNamespaceKeysToDelete returns the system.namespace keys of every name
+// held by the schema, namely its current name and all of its draining names.
+// It is used when the schema is finally dropped so that all of its namespace
+// entries can be removed in a single batch.
+func (desc *Immutable) AllNamespaceKeysToDelete(codec keys.SQLCodec) []roachpb.Key {
+	ret := make([]roachpb.Key, 0, len(desc.DrainingNames)+1)
+	seen := make(map[descpb.NameInfo]struct{}, len(desc.DrainingNames)+1)
+	add := func(ni descpb.NameInfo) {
+		if _, ok := seen[ni]; ok {
+			return
+		}
+		seen[ni] = struct{}{}
+		k := codec.IndexPrefix(keys.NamespaceTableID, namespaceTablePrimaryIndexID)
+		k = encoding.EncodeUvarintAscending(k, uint64(ni.ParentID))
+		k = encoding.EncodeUvarintAscending(k, uint64(ni.ParentSchemaID))
+		k = encoding.EncodeBytesAscending(k, []byte(ni.Name))
+		ret = append(ret, keys.MakeFamilyKey(k, namespaceTableIDFamilyID))
+	}
+	add(descpb.NameInfo{
 		ParentID:       desc.ParentID,
 		ParentSchemaID: keys.RootNamespaceID,
 		Name:           desc.Name,
 	})
+	for _, ni := range desc.DrainingNames {
+		add(ni)
+	}
+	return ret
+}
+
+// SetName sets the name of the schema. It handles installing a draining name
+// for the old name of the descriptor, unless the name is unchanged or the old
+// name is already draining, and removes the new name from the draining names,
+// e.g. after renaming the schema back and forth in one transaction. An error
+// is returned if the schema cannot be renamed.
+func (desc *Mutable) SetName(name string) error {
+	if !desc.CanBeRenamed() {
+		return pgerror.Newf(pgcode.InvalidSchemaName, "cannot rename schema %q", desc.Name)
+	}
+	if name == desc.Name {
+		return nil
+	}
+	oldName := descpb.NameInfo{
+		ParentID:       desc.ParentID,
+		ParentSchemaID: keys.RootNamespaceID,
+		Name:           desc.Name,
+	}
+	newName := descpb.NameInfo{
+		ParentID:       desc.ParentID,
+		ParentSchemaID: keys.RootNamespaceID,
+		Name:           name,
+	}
+	// The new name must no longer be draining, or renaming the schema back to a
+	// name it had earlier in the transaction would leave the name both in use
+	// and queued for deletion. A new slice is built so that the draining names
+	// of the cluster version are left untouched.
+	drainingNames := make([]descpb.NameInfo, 0, len(desc.DrainingNames)+1)
+	draining := false
+	for _, drain := range desc.DrainingNames {
+		if drain == newName {
+			continue
+		}
+		if drain == oldName {
+			draining = true
+		}
+		drainingNames = append(drainingNames, drain)
+	}
+	if !draining {
+		drainingNames = append(drainingNames, oldName)
+	}
+	desc.DrainingNames = drainingNames
 	desc.Name = name
+	return nil
+}
+
+// GetComment returns the comment on the schema, and whether it has one.
+func (desc *Immutable) GetComment() (string, bool) {
+	if desc.Comment == nil {
+		return "", false
+	}
+	return *desc.Comment, true
+}
+
+// SetComment sets the comment on the schema. A new string is allocated rather
+// than overwriting the existing one, which may be shared with the cluster
+// version snapshot.
+func (desc *Mutable) SetComment(comment string) {
+	desc.Comment = &comment
+}
+
+// DropComment removes the comment on the schema, if any. Use ClearComment to
+// learn whether there was a comment to remove.
+func (desc *Mutable) DropComment() {
+	desc.ClearComment()
+}
+
+// ClearComment removes the comment on the schema, if any. It returns whether
+// a comment was removed so that callers can avoid bumping the descriptor
+// version when the schema had no comment to begin with.
+func (desc *Mutable) ClearComment() bool {
+	if desc.Comment == nil {
+		return false
+	}
+	desc.Comment = nil
+	return true
+}
+
+// MaxObjectsNeedingLeaseRefresh bounds the number of IDs returned by
+// ObjectsNeedingLeaseRefresh. Leases on any further objects are not refreshed
+// proactively and pick up the new name when they expire.
+const MaxObjectsNeedingLeaseRefresh = 10000
+
+// ObjectsNeedingLeaseRefresh returns the IDs of the objects in the schema
+// whose leases should be refreshed after the schema is renamed, as their
+// qualified names have changed. The IDs are returned in ascending order. At
+// most MaxObjectsNeedingLeaseRefresh IDs are returned, and truncated is set if
+// the schema holds more objects than that. The lookup stops as soon as ctx is
+// canceled.
+func (desc *Immutable) ObjectsNeedingLeaseRefresh(
+	ctx context.Context, vdg catalog.ValidationDescGetter,
+) (ids []descpb.ID, truncated bool, err error) {
+	objectIDs, err := vdg.GetObjectIDsInSchema(ctx, desc.ParentID, desc.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(objectIDs) > MaxObjectsNeedingLeaseRefresh {
+		ids = make([]descpb.ID, 0, MaxObjectsNeedingLeaseRefresh)
+	} else {
+		ids = make([]descpb.ID, 0, len(objectIDs))
+	}
+	for _, id := range objectIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if len(ids) == MaxObjectsNeedingLeaseRefresh {
+			return ids, true, nil
+		}
+		ids = append(ids, id)
+	}
+	return ids, false, nil
+}
+
+// ReferencingViews returns the IDs of the views which depend on the tables,
+// views, sequences or types in the schema, in ascending order and without
+// duplicates. The views themselves may live inside or outside of the schema.
+func (desc *Immutable) ReferencingViews(
+	ctx context.Context, vdg catalog.ValidationDescGetter,
+) ([]descpb.ID, error) {
+	return desc.dependentViews(ctx, vdg, true /* includeTypeRefs */, catalog.TableDescriptor.IsView)
+}
+
+// RenameAffectsMaterializedViews returns the IDs of the materialized views
+// which would be affected by renaming the schema, in ascending order. View
+// queries refer to tables, views and sequences by their qualified names, so
+// materialized views depending on any of those in the schema are affected.
+// Types are referenced by OID and do not make a view depend on the name of
+// their schema.
+func (desc *Immutable) RenameAffectsMaterializedViews(
+	ctx context.Context, vdg catalog.ValidationDescGetter,
+) ([]descpb.ID, error) {
+	return desc.dependentViews(ctx, vdg, false /* includeTypeRefs */, catalog.TableDescriptor.MaterializedView)
+}
+
+// dependentViews returns the sorted, deduplicated IDs of the descriptors
+// satisfying pred which depend on the objects in the schema. Dependencies on
+// types are only followed if includeTypeRefs is set.
+func (desc *Immutable) dependentViews(
+	ctx context.Context,
+	vdg catalog.ValidationDescGetter,
+	includeTypeRefs bool,
+	pred func(catalog.TableDescriptor) bool,
+) ([]descpb.ID, error) {
+	objectIDs, err := vdg.GetObjectIDsInSchema(ctx, desc.ParentID, desc.ID)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := vdg.GetDescs(ctx, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+	dependents := make(map[descpb.ID]struct{})
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case catalog.TableDescriptor:
+			for _, ref := range obj.TableDesc().DependedOnBy {
+				dependents[ref.ID] = struct{}{}
+			}
+		case catalog.TypeDescriptor:
+			if !includeTypeRefs {
+				continue
+			}
+			for _, id := range obj.TypeDesc().ReferencingDescriptorIDs {
+				dependents[id] = struct{}{}
+			}
+		}
+	}
+	var ret descpb.IDs
+	for id := range dependents {
+		dep, err := vdg.GetDesc(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if tbl, ok := dep.(catalog.TableDescriptor); ok && pred(tbl) {
+			ret = append(ret, id)
+		}
+	}
+	sort.Sort(ret)
+	return ret, nil
+}
+
+// SearchPathRank returns the position of the schema in the effective search
+// path of the session, as iterated over by name resolution. This includes the
+// implicitly searched pg_catalog, pg_extension and temporary schemas. The
+// pg_temp alias stands for the temporary schema of the session, which must
+// also have the session's temporary schema ID, and is skipped if the session
+// has none. $user stands for a user defined schema named after the session
+// user, and is skipped if there is no session user. If the schema is not on
+// the search path, (-1, false) is returned.
+func (desc *Immutable) SearchPathRank(sd *sessiondata.SessionData) (int, bool) {
+	tempSchemaName := sd.SearchPath.GetTemporarySchemaName()
+	iter := sd.SearchPath.Iter()
+	for rank := 0; ; {
+		path, ok := iter.Next()
+		if !ok {
+			return -1, false
+		}
+		var match bool
+		switch path {
+		case "$user":
+			if sd.User == "" {
+				continue
+			}
+			match = desc.Name == sd.User && desc.GetSchemaKind() == catalog.SchemaUserDefined
+		case tempSchemaName:
+			match = desc.Name == path && desc.ID == descpb.ID(sd.TemporarySchemaID)
+		default:
+			match = desc.Name == path
+		}
+		if match {
+			return rank, true
+		}
+		rank++
+	}
+}
+
+// IsTemporary returns whether the descriptor is that of a session-scoped
+// temporary schema, as made by NewTemporarySchema. A descriptor which merely
+// has a pg_temp name is not temporary, and fails validation.
+func (desc *Immutable) IsTemporary() bool {
+	return desc.temporary
 }
 
+// IsVirtual returns whether the descriptor is for a virtual schema, as made by
+// NewVirtualSchema.
+func (desc *Immutable) IsVirtual() bool {
+	return desc.virtual
+}
+
+// TemporarySessionID returns the ID of the session which owns the temporary
+// schema, as encoded in its pg_temp_<hi>_<lo> name. It is the value of the
+// session's sql.ClusterWideID. If the schema is not a temporary schema or its
+// name is malformed, false is returned.
+func (desc *Immutable) TemporarySessionID() (uint128.Uint128, bool) {
+	if desc.GetSchemaKind() != catalog.SchemaTemporary {
+		return uint128.Uint128{}, false
+	}
+	parts := strings.Split(desc.Name, "_")
+	if len(parts) != 4 {
+		return uint128.Uint128{}, false
+	}
+	hi, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return uint128.Uint128{}, false
+	}
+	lo, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return uint128.Uint128{}, false
+	}
+	return uint128.Uint128{Hi: hi, Lo: lo}, true
+}
+
+// GetSchemaKind classifies the schema the way name resolution does, as one of
+// the public, virtual, temporary or user-defined schemas. Public, virtual and
+// temporary schemas are not backed by descriptors in storage, but descriptors
+// may be synthesized for them. Public schemas are recognized by their reserved
+// ID or name, virtual schemas by their name or the marker set by
+// NewVirtualSchema, and temporary schemas by the marker set by
+// NewTemporarySchema.
+func (desc *Immutable) GetSchemaKind() catalog.ResolvedSchemaKind {
+	switch {
+	case desc.ID == keys.PublicSchemaID || desc.Name == sessiondata.PublicSchemaName:
+		return catalog.SchemaPublic
+	case desc.virtual || isVirtualSchemaName(desc.Name):
+		return catalog.SchemaVirtual
+	case desc.temporary:
+		return catalog.SchemaTemporary
+	default:
+		return catalog.SchemaUserDefined
+	}
+}
+
+// isVirtualSchemaName returns whether name is the name of one of the virtual
+// schemas present in every database.
+func isVirtualSchemaName(name string) bool {
+	switch name {
+	case sessiondata.PgCatalogName, sessiondata.InformationSchemaName,
+		sessiondata.CRDBInternalSchemaName, sessiondata.PgExtensionSchemaName:
+		return true
+	}
+	return false
+}
+
+// MaxSchemaNameLength is the maximum length in bytes of a user defined schema
+// name. It matches the identifier limit enforced by Postgres (NAMEDATALEN-1).
+const MaxSchemaNameLength = 63
+
 // IsSchemaNameValid returns whether the input name is valid for a user defined
-// schema.
+// schema. The name must be non-empty, at most MaxSchemaNameLength bytes long
+// and free of control characters. It must not start with "pg_", nor match the
+// name of the public schema or of a virtual schema in any case. Some stored
+// schemas predate these rules; ValidateSelf reports them, but they are not
+// checked when descriptors are read, so that such schemas can be renamed or
+// dropped. The returned errors carry a telemetry key naming the rule which
+// rejected the name; see sqltelemetry.InvalidSchemaNameCounterPrefix.
 func IsSchemaNameValid(name string) error {
+	if name == "" {
+		return errors.WithTelemetry(pgerror.New(pgcode.InvalidName, "empty schema name"),
+			sqltelemetry.InvalidSchemaNameCounterPrefix+"empty")
+	}
+	if len(name) > MaxSchemaNameLength {
+		return errors.WithTelemetry(pgerror.Newf(pgcode.NameTooLong,
+			"schema name is %d bytes long, exceeding the maximum of %d bytes",
+			len(name), MaxSchemaNameLength),
+			sqltelemetry.InvalidSchemaNameCounterPrefix+"too_long")
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return errors.WithTelemetry(pgerror.Newf(pgcode.InvalidName,
+				"schema name %q contains invalid control character %U", name, r),
+				sqltelemetry.InvalidSchemaNameCounterPrefix+"control_character")
+		}
+	}
 	// Schemas starting with "pg_" are not allowed.
 	if strings.HasPrefix(name, sessiondata.PgSchemaPrefix) {
 		err := pgerror.Newf(pgcode.ReservedName, "unacceptable schema name %q", name)
 		err = errors.WithDetail(err, `The prefix "pg_" is reserved for system schemas.`)
-		return err
+		return errors.WithTelemetry(err, sqltelemetry.InvalidSchemaNameCounterPrefix+"pg_prefix")
+	}
+	// Schemas which would shadow a virtual schema or the public schema are not
+	// allowed, regardless of case.
+	for _, reserved := range []string{
+		sessiondata.CRDBInternalSchemaName,
+		sessiondata.InformationSchemaName,
+		sessiondata.PublicSchemaName,
+	} {
+		if strings.EqualFold(name, reserved) {
+			err := pgerror.Newf(pgcode.ReservedName, "unacceptable schema name %q", name)
+			err = errors.WithDetailf(err, "The name %q is reserved for a system schema.", reserved)
+			return errors.WithTelemetry(err, sqltelemetry.InvalidSchemaNameCounterPrefix+reserved)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,2917 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server/telemetry"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkeys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/dbdesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqltelemetry"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uint128"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDropPreconditions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	const dbID, scID = 50, 51
+	table := tabledesc.NewImmutable(descpb.TableDescriptor{
+		ID:                      52,
+		Name:                    "t",
+		ParentID:                dbID,
+		UnexposedParentSchemaID: scID,
+	})
+
+	for _, tc := range []struct {
+		name     string
+		desc     descpb.SchemaDescriptor
+		contents catalog.MapDescGetter
+		behavior tree.DropBehavior
+		err      error
+	}{
+		{
+			name:     "empty",
+			desc:     descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"},
+			contents: catalog.MapDescGetter{},
+		},
+		{
+			name:     "not empty",
+			desc:     descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"},
+			contents: catalog.MapDescGetter{table.GetID(): table},
+			err:      schemadesc.ErrSchemaNotEmpty,
+		},
+		{
+			name:     "not empty restrict",
+			desc:     descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"},
+			contents: catalog.MapDescGetter{table.GetID(): table},
+			behavior: tree.DropRestrict,
+			err:      schemadesc.ErrSchemaNotEmpty,
+		},
+		{
+			name:     "not empty cascade",
+			desc:     descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"},
+			contents: catalog.MapDescGetter{table.GetID(): table},
+			behavior: tree.DropCascade,
+		},
+		{
+			name: "dropped",
+			desc: descpb.SchemaDescriptor{
+				ID: scID, ParentID: dbID, Name: "sc", State: descpb.SchemaDescriptor_DROP,
+			},
+			contents: catalog.MapDescGetter{},
+			behavior: tree.DropCascade,
+			err:      schemadesc.ErrSchemaAlreadyDropped,
+		},
+		{
+			name: "adding",
+			desc: descpb.SchemaDescriptor{
+				ID: scID, ParentID: dbID, Name: "sc", State: descpb.SchemaDescriptor_ADD,
+			},
+			contents: catalog.MapDescGetter{},
+			err:      schemadesc.ErrSchemaNotPublic,
+		},
+		{
+			name: "offline",
+			desc: descpb.SchemaDescriptor{
+				ID: scID, ParentID: dbID, Name: "sc", State: descpb.SchemaDescriptor_OFFLINE,
+			},
+			contents: catalog.MapDescGetter{},
+			behavior: tree.DropCascade,
+			err:      schemadesc.ErrSchemaNotPublic,
+		},
+		{
+			name:     "public",
+			desc:     descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: dbID, Name: "public"},
+			contents: catalog.MapDescGetter{},
+			behavior: tree.DropCascade,
+			err:      schemadesc.ErrSchemaCannotBeDropped,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schemadesc.NewImmutable(tc.desc).ValidateDropPreconditions(ctx, tc.contents, tc.behavior)
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestSetNameRejectsNonUserDefinedSchemas(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc       descpb.SchemaDescriptor
+		canRename  bool
+		errPattern string
+	}{
+		{desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}, canRename: true},
+		{
+			desc:       descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"},
+			errPattern: `cannot rename schema "public"`,
+		},
+		{
+			desc:       descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_catalog"},
+			errPattern: `cannot rename schema "pg_catalog"`,
+		},
+	} {
+		t.Run(tc.desc.Name, func(t *testing.T) {
+			mut := schemadesc.NewMutableExisting(tc.desc)
+			require.Equal(t, tc.canRename, mut.CanBeRenamed())
+			err := mut.SetName("new_name")
+			if tc.canRename {
+				require.NoError(t, err)
+				require.Equal(t, "new_name", mut.GetName())
+			} else {
+				require.Regexp(t, tc.errPattern, err)
+				require.Equal(t, tc.desc.Name, mut.GetName())
+				require.Empty(t, mut.DrainingNames)
+			}
+		})
+	}
+	require.False(t, schemadesc.NewTemporarySchema("pg_temp_1_1", 51, 50).CanBeRenamed())
+}
+
+func TestReconcilePublicSchemaPrivileges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	db := dbdesc.NewInitial(50, "db", security.AdminRole)
+	db.Privileges.Grant("foo", privilege.List{privilege.CREATE})
+
+	public := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: keys.PublicSchemaID, ParentID: 50, Name: "public",
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+	})
+	require.True(t, schemadesc.ReconcilePublicSchemaPrivileges(public, db))
+	require.Equal(t, db.GetPrivileges(), public.GetPrivileges())
+	require.False(t, schemadesc.ReconcilePublicSchemaPrivileges(public, db))
+
+	// The privileges are copied, not shared with the database.
+	db.Privileges.Grant("bar", privilege.List{privilege.CREATE})
+	require.NotEqual(t, db.GetPrivileges(), public.GetPrivileges())
+
+	userDefined := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+	})
+	require.False(t, schemadesc.ReconcilePublicSchemaPrivileges(userDefined, db))
+}
+
+func TestSortKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var descs []*schemadesc.Immutable
+	for _, name := range []string{"b", "Data", "pg_catalog", "a", "public", "data", "crdb_internal"} {
+		descs = append(descs, schemadesc.NewImmutable(descpb.SchemaDescriptor{Name: name}))
+	}
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].SortKey() < descs[j].SortKey()
+	})
+	var names []string
+	for _, desc := range descs {
+		names = append(names, desc.GetName())
+	}
+	require.Equal(t, []string{"public", "crdb_internal", "pg_catalog", "a", "b", "Data", "data"}, names)
+}
+
+func TestValidateSelfPrivilegeVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	privs := descpb.NewDefaultPrivilegeDescriptor(security.AdminRole)
+	desc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Privileges: privs}
+	var errs catalog.ValidationErrors
+	schemadesc.NewImmutable(desc).ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	privs.Version = descpb.OwnerVersion + 1
+	schemadesc.NewImmutable(desc).ValidateSelf(&errs)
+	require.Regexp(t, `privilege descriptor version 2, but the newest supported version is 1`,
+		errs.CombinedError())
+}
+
+func TestHasCaseInsensitiveCollision(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	existing := []string{"sales", "Data", "İstanbul"}
+	for _, tc := range []struct {
+		name      string
+		collision string
+	}{
+		{name: "data", collision: "Data"},
+		{name: "DATA", collision: "Data"},
+		{name: "SALES", collision: "sales"},
+		{name: "istanbul", collision: "İstanbul"},
+		{name: "marketing"},
+	} {
+		collision, ok := schemadesc.HasCaseInsensitiveCollision(tc.name, existing)
+		require.Equal(t, tc.collision != "", ok, tc.name)
+		require.Equal(t, tc.collision, collision, tc.name)
+	}
+}
+
+func TestValidateSelfNodeOwner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.NodeUser),
+	}
+
+	var errs catalog.ValidationErrors
+	schemadesc.NewImmutable(desc).ValidateSelf(&errs)
+	err := errs.CombinedError()
+	require.Regexp(t, `schema "sc" \(51\) is owned by the internal user "node"`, err)
+	require.Contains(t, errors.FlattenHints(err), "REASSIGN OWNED")
+
+	desc.Privileges.SetOwner("alice")
+	errs = nil
+	schemadesc.NewImmutable(desc).ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+}
+
+func TestMakeDefaultPublicSchemaForDatabase(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	db := dbdesc.NewInitial(50, "db", security.AdminRole)
+	desc := schemadesc.MakeDefaultPublicSchemaForDatabase(db.DatabaseDesc(), 51)
+	require.Equal(t, "public", desc.Name)
+	require.Equal(t, descpb.ID(51), desc.ID)
+	require.Equal(t, descpb.ID(50), desc.ParentID)
+	require.Equal(t, db.GetPrivileges(), desc.Privileges)
+	require.False(t, db.GetPrivileges() == desc.Privileges)
+}
+
+func TestUserSchemasInDatabase(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	db := dbdesc.NewInitial(50, "db", security.AdminRole).DatabaseDesc()
+	db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{
+		"b":    {ID: 51},
+		"a":    {ID: 52},
+		"gone": {ID: 53, Dropped: true},
+	}
+	getter := catalog.MapDescGetter{
+		51: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "b"}),
+		52: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 52, ParentID: 50, Name: "a"}),
+	}
+	names, err := schemadesc.UserSchemasInDatabase(ctx, dbdesc.NewImmutable(*db), getter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, names)
+
+	// A schema entry pointing to a non-schema descriptor is a corruption.
+	getter[52] = dbdesc.NewImmutable(*db)
+	_, err = schemadesc.UserSchemasInDatabase(ctx, dbdesc.NewImmutable(*db), getter)
+	require.True(t, errors.HasAssertionFailure(err))
+}
+
+func TestValidateSelfConvertedFromDatabase(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc descpb.SchemaDescriptor
+		err  string
+	}{
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"},
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", ConvertedFromDatabaseID: 49},
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", ConvertedFromDatabaseID: 50},
+			err:  `schema "sc" \(51\) with parent 50 has invalid converted database ID 50`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", ConvertedFromDatabaseID: 51},
+			err:  `schema "sc" \(51\) with parent 50 has invalid converted database ID 51`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "public", ConvertedFromDatabaseID: 49},
+			err:  `schema "public" \(51\) cannot be converted from database 49`,
+		},
+	} {
+		var errs catalog.ValidationErrors
+		schemadesc.NewImmutable(tc.desc).ValidateSelf(&errs)
+		if tc.err == "" {
+			require.NoError(t, errs.CombinedError())
+		} else {
+			require.Regexp(t, tc.err, errs.CombinedError())
+		}
+	}
+}
+
+func TestCanSwapNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	a := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "blue"})
+	b := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 52, ParentID: 50, Name: "green"})
+	other := func(id, parentID descpb.ID, name string, draining ...string) catalog.SchemaDescriptor {
+		desc := descpb.SchemaDescriptor{ID: id, ParentID: parentID, Name: name}
+		for _, n := range draining {
+			desc.DrainingNames = append(desc.DrainingNames, descpb.NameInfo{ParentID: parentID, Name: n})
+		}
+		return schemadesc.NewImmutable(desc)
+	}
+
+	require.NoError(t, schemadesc.CanSwapNames(a, b, []catalog.SchemaDescriptor{
+		a, b, other(53, 50, "red", "yellow"), other(54, 60, "blue", "green"),
+	}))
+	require.Regexp(t, `name "green" is in use by schema 53`,
+		schemadesc.CanSwapNames(a, b, []catalog.SchemaDescriptor{other(53, 50, "red", "green")}))
+	require.Regexp(t, `name "blue" is in use by schema 53`,
+		schemadesc.CanSwapNames(a, b, []catalog.SchemaDescriptor{other(53, 50, "blue")}))
+
+	c := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 55, ParentID: 60, Name: "c"})
+	require.True(t, errors.HasAssertionFailure(schemadesc.CanSwapNames(a, c, nil)))
+}
+
+func TestValidateSelfDisableAutoStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", DisableAutoStats: true}
+	var errs catalog.ValidationErrors
+	sc := schemadesc.NewImmutable(desc)
+	require.True(t, sc.AutoStatsDisabled())
+	sc.ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	desc.Name = "public"
+	schemadesc.NewImmutable(desc).ValidateSelf(&errs)
+	require.Regexp(t, `automatic statistics cannot be disabled for schema "public" \(51\)`,
+		errs.CombinedError())
+}
+
+func TestChecksumContribution(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	base := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+	}
+	sum := schemadesc.NewImmutable(base).ChecksumContribution()
+
+	// The version and modification time do not contribute.
+	same := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+	same.Version = 7
+	same.ModificationTime = hlc.Timestamp{WallTime: 100}
+	require.Equal(t, sum, schemadesc.NewImmutable(same).ChecksumContribution())
+
+	for _, mutate := range []func(*descpb.SchemaDescriptor){
+		func(d *descpb.SchemaDescriptor) { d.ID = 52 },
+		func(d *descpb.SchemaDescriptor) { d.ParentID = 49 },
+		func(d *descpb.SchemaDescriptor) { d.Name = "sc2" },
+		func(d *descpb.SchemaDescriptor) { d.Privileges.SetOwner("alice") },
+		func(d *descpb.SchemaDescriptor) { d.State = descpb.SchemaDescriptor_DROP },
+	} {
+		changed := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+		mutate(&changed)
+		require.NotEqual(t, sum, schemadesc.NewImmutable(changed).ChecksumContribution())
+	}
+
+	a := schemadesc.NewImmutable(base)
+	b := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 52, ParentID: 50, Name: "other"})
+	require.Equal(t,
+		schemadesc.CombineChecksumContributions([]*schemadesc.Immutable{a, b}),
+		schemadesc.CombineChecksumContributions([]*schemadesc.Immutable{b, a}))
+}
+
+func TestNameRequiresQuoting(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for name, expected := range map[string]bool{
+		"sc":        false,
+		"sc_1":      false,
+		"public":    false,
+		"":          true,
+		"Sc":        true,
+		"my schema": true,
+		"1sc":       true,
+		"select":    true,
+		`s"c`:       true,
+	} {
+		require.Equal(t, expected, schemadesc.NameRequiresQuoting(name), "%q", name)
+	}
+}
+
+func TestObjectsNeedingLeaseRefresh(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	getter := catalog.MapDescGetter{}
+	for _, tbl := range []descpb.TableDescriptor{
+		{ID: 53, Name: "b", ParentID: 50, UnexposedParentSchemaID: 51},
+		{ID: 52, Name: "a", ParentID: 50, UnexposedParentSchemaID: 51},
+		{ID: 54, Name: "c", ParentID: 50, UnexposedParentSchemaID: keys.PublicSchemaID},
+	} {
+		getter[tbl.ID] = tabledesc.NewImmutable(tbl)
+	}
+	ids, truncated, err := sc.ObjectsNeedingLeaseRefresh(ctx, getter)
+	require.NoError(t, err)
+	require.False(t, truncated)
+	require.Equal(t, []descpb.ID{52, 53}, ids)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = sc.ObjectsNeedingLeaseRefresh(canceled, getter)
+	require.True(t, errors.Is(err, context.Canceled))
+
+	// IDs beyond the limit are not dropped silently.
+	for id := descpb.ID(100); id < 100+schemadesc.MaxObjectsNeedingLeaseRefresh; id++ {
+		getter[id] = tabledesc.NewImmutable(descpb.TableDescriptor{
+			ID: id, Name: fmt.Sprintf("t%d", id), ParentID: 50, UnexposedParentSchemaID: 51,
+		})
+	}
+	ids, truncated, err = sc.ObjectsNeedingLeaseRefresh(ctx, getter)
+	require.NoError(t, err)
+	require.True(t, truncated)
+	require.Len(t, ids, schemadesc.MaxObjectsNeedingLeaseRefresh)
+	require.Equal(t, descpb.ID(52), ids[0])
+}
+
+func TestClearComment(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	comment := "hello"
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Comment: &comment,
+	})
+	require.True(t, sc.ClearComment())
+	require.Nil(t, sc.Comment)
+	require.False(t, sc.ClearComment())
+	// The cluster version snapshot retains the comment.
+	comment, ok := sc.ClusterVersion.GetComment()
+	require.True(t, ok)
+	require.Equal(t, "hello", comment)
+}
+
+func TestComment(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	_, ok := sc.GetComment()
+	require.False(t, ok)
+
+	sc.SetComment("hello")
+	comment, ok := sc.GetComment()
+	require.True(t, ok)
+	require.Equal(t, "hello", comment)
+	_, ok = sc.ClusterVersion.GetComment()
+	require.False(t, ok)
+
+	// The comment round-trips through the descriptor proto.
+	buf, err := protoutil.Marshal(sc.DescriptorProto())
+	require.NoError(t, err)
+	var decoded descpb.Descriptor
+	require.NoError(t, protoutil.Unmarshal(buf, &decoded))
+	comment, ok = schemadesc.NewImmutable(*decoded.GetSchema()).GetComment()
+	require.True(t, ok)
+	require.Equal(t, "hello", comment)
+
+	// Setting the comment again does not write through to the cluster version.
+	existing := schemadesc.NewMutableExisting(*decoded.GetSchema())
+	existing.SetComment("bye")
+	comment, _ = existing.ClusterVersion.GetComment()
+	require.Equal(t, "hello", comment)
+
+	sc.DropComment()
+	_, ok = sc.GetComment()
+	require.False(t, ok)
+	sc.DropComment()
+}
+
+func TestValidateSelfParentIDCollision(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var errs catalog.ValidationErrors
+	schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 50, ParentID: 50, Name: "sc"}).ValidateSelf(&errs)
+	err := errs.CombinedError()
+	require.Regexp(t, `schema "sc" \(50\) has the same ID as its parent database`, err)
+	require.True(t, errors.HasAssertionFailure(err))
+}
+
+func TestValidateSelfDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	drain := func(name string) descpb.NameInfo {
+		return descpb.NameInfo{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: name}
+	}
+	for _, tc := range []struct {
+		name     string
+		draining []descpb.NameInfo
+		err      string
+	}{
+		{name: "clean", draining: []descpb.NameInfo{drain("a"), drain("b")}},
+		{
+			name:     "live name draining",
+			draining: []descpb.NameInfo{drain("a"), drain("sc")},
+			err:      `schema "sc" \(51\) has its current name as a draining name`,
+		},
+		{
+			name:     "duplicate draining names",
+			draining: []descpb.NameInfo{drain("a"), drain("b"), drain("a")},
+			err:      `schema "sc" \(51\) has duplicate draining name "a"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var errs catalog.ValidationErrors
+			schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc", DrainingNames: tc.draining,
+			}).ValidateSelf(&errs)
+			err := errs.CombinedError()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Regexp(t, tc.err, err)
+			require.True(t, errors.HasAssertionFailure(err))
+		})
+	}
+}
+
+func TestValidateSelfIdentity(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc descpb.SchemaDescriptor
+		err  string
+	}{
+		{desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Version: 1}},
+		{desc: descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"}},
+		{
+			// Only descriptors made by NewTemporarySchema are temporary.
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_temp_1_2"},
+			err:  `unacceptable schema name "pg_temp_1_2"`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50},
+			err:  `empty schema name`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_sc"},
+			err:  `unacceptable schema name "pg_sc"`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ParentID: 50, Name: "sc"},
+			err:  `schema "sc" has invalid ID 0`,
+		},
+		{
+			desc: descpb.SchemaDescriptor{ID: 51, Name: "sc"},
+			err:  `schema "sc" \(51\) has invalid parent ID 0`,
+		},
+	} {
+		var errs catalog.ValidationErrors
+		schemadesc.NewImmutable(tc.desc).ValidateSelf(&errs)
+		if tc.err == "" {
+			require.NoError(t, errs.CombinedError(), "%+v", tc.desc)
+		} else {
+			require.Regexp(t, tc.err, errs.CombinedError(), "%+v", tc.desc)
+		}
+	}
+}
+
+func TestValidateIdentity(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Descriptors which only violate rules beyond their identity can still be
+	// read.
+	newerPrivs := descpb.NewDefaultPrivilegeDescriptor(security.AdminRole)
+	newerPrivs.Version = descpb.OwnerVersion + 1
+	for _, desc := range []descpb.SchemaDescriptor{
+		{ID: 51, ParentID: 50, Name: "pg_sc"},
+		{ID: 51, ParentID: 50, Name: "sc", Privileges: newerPrivs},
+		{ID: 51, ParentID: 50, Name: "sc", DefaultTableTTL: -1},
+	} {
+		var errs catalog.ValidationErrors
+		sc := schemadesc.NewImmutable(desc)
+		sc.ValidateSelf(&errs)
+		require.Error(t, errs.CombinedError(), "%+v", desc)
+		errs = nil
+		sc.ValidateIdentity(&errs)
+		require.NoError(t, errs.CombinedError(), "%+v", desc)
+	}
+
+	for desc, expected := range map[*descpb.SchemaDescriptor]string{
+		{ID: 51, ParentID: 50}:             `empty schema name`,
+		{ParentID: 50, Name: "sc"}:         `schema "sc" has invalid ID 0`,
+		{ID: 51, Name: "sc"}:               `schema "sc" \(51\) has invalid parent ID 0`,
+		{ID: 50, ParentID: 50, Name: "sc"}: `schema "sc" \(50\) has the same ID as its parent database`,
+	} {
+		var errs catalog.ValidationErrors
+		schemadesc.NewImmutable(*desc).ValidateIdentity(&errs)
+		require.Regexp(t, expected, errs.CombinedError(), "%+v", desc)
+	}
+}
+
+func TestRenameWrites(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	setup := func() (*schemadesc.Mutable, *dbdesc.Mutable) {
+		db := dbdesc.NewInitial(50, "db", security.AdminRole).DatabaseDesc()
+		db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{
+			"sc":    {ID: 51},
+			"other": {ID: 52},
+			"old":   {ID: 53, Dropped: true},
+		}
+		sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: "sc", Version: 1,
+		})
+		return sc, dbdesc.NewExistingMutable(*db)
+	}
+
+	sc, db := setup()
+	require.NoError(t, schemadesc.RenameWrites(sc, db, "renamed"))
+	require.Equal(t, "renamed", sc.Name)
+	require.Equal(t, descpb.DescriptorVersion(2), sc.Version)
+	require.Equal(t, []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "sc"},
+	}, sc.DrainingNames)
+	require.Equal(t, descpb.DatabaseDescriptor_SchemaInfo{ID: 51, Dropped: true}, db.Schemas["sc"])
+	require.Equal(t, descpb.DatabaseDescriptor_SchemaInfo{ID: 51}, db.Schemas["renamed"])
+	require.Equal(t, db.ClusterVersion.Version+1, db.Version)
+
+	for _, tc := range []struct {
+		newName string
+		err     string
+	}{
+		{newName: "other", err: `schema "other" already exists`},
+		{newName: "old", err: `schema name "old" is still being released`},
+		{newName: "pg_sc", err: `unacceptable schema name "pg_sc"`},
+	} {
+		sc, db := setup()
+		before := protoutil.Clone(db.DatabaseDesc()).(*descpb.DatabaseDescriptor)
+		require.Regexp(t, tc.err, schemadesc.RenameWrites(sc, db, tc.newName))
+		require.Equal(t, "sc", sc.Name)
+		require.Empty(t, sc.DrainingNames)
+		require.Equal(t, before, db.DatabaseDesc())
+	}
+}
+
+func TestEligibleForFastDrop(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Version: 1}
+	require.True(t, schemadesc.NewMutableCreatedSchemaDescriptor(desc).EligibleForFastDrop())
+	require.False(t, schemadesc.NewMutableExisting(desc).EligibleForFastDrop())
+
+	desc.State = descpb.SchemaDescriptor_DROP
+	require.False(t, schemadesc.NewMutableCreatedSchemaDescriptor(desc).EligibleForFastDrop())
+}
+
+func TestReassignOwner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+	})
+	require.False(t, sc.ReassignOwner("bob", "carol"))
+	require.Equal(t, "alice", sc.Privileges.Owner)
+	require.True(t, sc.ReassignOwner("alice", "bob"))
+	require.Equal(t, "bob", sc.Privileges.Owner)
+	require.False(t, sc.ReassignOwner("bob", "bob"))
+	// The cluster version snapshot is unaffected.
+	require.Equal(t, "alice", sc.ClusterVersion.Privileges.Owner)
+}
+
+// namespaceOverrideGetter is a catalog.ValidationDescGetter whose namespace
+// entries are given explicitly rather than derived from the descriptors.
+type namespaceOverrideGetter struct {
+	catalog.MapDescGetter
+	namespace map[string]descpb.ID
+}
+
+func (g namespaceOverrideGetter) GetNamespaceEntries(
+	ctx context.Context, parentID, parentSchemaID descpb.ID,
+) (map[string]descpb.ID, error) {
+	return g.namespace, nil
+}
+
+func TestValidateCrossReferencesNamespaceEntry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "Sc"})
+	db := dbdesc.NewInitial(50, "db", security.AdminRole)
+	db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{"Sc": {ID: 51}}
+	for _, tc := range []struct {
+		namespace map[string]descpb.ID
+		err       string
+	}{
+		{namespace: map[string]descpb.ID{"Sc": 51}},
+		{namespace: map[string]descpb.ID{"Sc": 51, "old": 51}},
+		{
+			namespace: map[string]descpb.ID{"SC": 51},
+			err:       `schema "Sc" \(51\) has namespace entry "SC" which differs in case`,
+		},
+		{
+			namespace: map[string]descpb.ID{"Sc": 52},
+			err:       `schema "Sc" \(51\) has no namespace entry with the same name`,
+		},
+		{
+			namespace: map[string]descpb.ID{},
+			err:       `schema "Sc" \(51\) has no namespace entry with the same name`,
+		},
+	} {
+		var errs catalog.ValidationErrors
+		sc.ValidateCrossReferences(ctx, namespaceOverrideGetter{
+			MapDescGetter: catalog.MapDescGetter{db.GetID(): db},
+			namespace:     tc.namespace,
+		}, anyRoleExists{}, &errs)
+		if tc.err == "" {
+			require.NoError(t, errs.CombinedError())
+		} else {
+			require.Regexp(t, tc.err, errs.CombinedError())
+		}
+	}
+
+	// The namespace derived from the descriptors agrees with the descriptor.
+	var errs catalog.ValidationErrors
+	sc.ValidateCrossReferences(ctx, catalog.MapDescGetter{sc.GetID(): sc, db.GetID(): db}, anyRoleExists{}, &errs)
+	require.NoError(t, errs.CombinedError())
+}
+
+// existingRoles is a catalog.RoleExistenceChecker for which exactly the
+// roles in the map exist.
+type existingRoles map[string]bool
+
+var _ catalog.RoleExistenceChecker = existingRoles{}
+
+func (r existingRoles) RoleExists(ctx context.Context, role string) (bool, error) {
+	return r[role], nil
+}
+
+// anyRoleExists is a catalog.RoleExistenceChecker for which every role exists.
+type anyRoleExists struct{}
+
+var _ catalog.RoleExistenceChecker = anyRoleExists{}
+
+func (anyRoleExists) RoleExists(ctx context.Context, role string) (bool, error) {
+	return true, nil
+}
+
+func TestValidateCrossReferencesRolesExist(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	db := dbdesc.NewInitial(50, "db", security.AdminRole)
+	db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 51}}
+	for _, tc := range []struct {
+		name     string
+		owner    string
+		grantees []string
+		err      string
+	}{
+		{name: "valid owner", owner: "alice", grantees: []string{"bob", security.PublicRole}},
+		{name: "pseudo-roles", owner: security.RootUser},
+		{
+			name:  "dropped owner",
+			owner: "carol",
+			err:   `schema "sc" \(51\) is owned by role "carol", which does not exist`,
+		},
+		{
+			name:     "dropped grantee",
+			owner:    "alice",
+			grantees: []string{"bob", "dave"},
+			err:      `schema "sc" \(51\) grants privileges to role "dave", which does not exist`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			privs := descpb.NewDefaultPrivilegeDescriptor(tc.owner)
+			for _, grantee := range tc.grantees {
+				privs.Grant(grantee, privilege.List{privilege.USAGE})
+			}
+			sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc", Privileges: privs,
+			})
+			getter := catalog.MapDescGetter{db.GetID(): db, sc.GetID(): sc}
+			roles := existingRoles{"alice": true, "bob": true}
+			var errs catalog.ValidationErrors
+			sc.ValidateCrossReferences(ctx, getter, roles, &errs)
+			if tc.err == "" {
+				require.NoError(t, errs.CombinedError())
+			} else {
+				require.Regexp(t, tc.err, errs.CombinedError())
+			}
+		})
+	}
+}
+
+func TestValidateCrossReferencesParentDatabase(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	makeDB := func(schemas map[string]descpb.DatabaseDescriptor_SchemaInfo) *dbdesc.Mutable {
+		db := dbdesc.NewInitial(50, "db", security.AdminRole)
+		db.Schemas = schemas
+		return db
+	}
+	listed := map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 51}}
+	droppedDB := makeDB(listed)
+	droppedDB.State = descpb.DatabaseDescriptor_DROP
+	sc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}
+	droppedSc := sc
+	droppedSc.State = descpb.SchemaDescriptor_DROP
+
+	for _, tc := range []struct {
+		name   string
+		schema descpb.SchemaDescriptor
+		parent catalog.Descriptor
+		err    string
+	}{
+		{name: "valid", schema: sc, parent: makeDB(listed)},
+		{
+			name:   "public schema is not listed",
+			schema: descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"},
+			parent: makeDB(nil),
+		},
+		{name: "dropped schema", schema: droppedSc, parent: droppedDB},
+		{
+			name:   "missing parent",
+			schema: sc,
+			err:    `schema "sc" \(51\) has missing parent database 50`,
+		},
+		{
+			name:   "parent is not a database",
+			schema: sc,
+			parent: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 50, ParentID: 49, Name: "other"}),
+			err:    `schema "sc" \(51\) has parent 50 which is a schema, not a database`,
+		},
+		{
+			name:   "dropped parent",
+			schema: sc,
+			parent: droppedDB,
+			err:    `schema "sc" \(51\) has dropped parent database "db" \(50\)`,
+		},
+		{
+			name:   "parent does not list schema",
+			schema: sc,
+			parent: makeDB(nil),
+			err:    `schema "sc" \(51\) is not in the schemas of its parent database "db" \(50\)`,
+		},
+		{
+			name:   "parent lists another ID",
+			schema: sc,
+			parent: makeDB(map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 52}}),
+			err:    `schema "sc" \(51\) is not in the schemas of its parent database "db" \(50\)`,
+		},
+		{
+			name:   "parent lists schema as dropped",
+			schema: sc,
+			parent: makeDB(map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 51, Dropped: true}}),
+			err:    `schema "sc" \(51\) is not in the schemas of its parent database "db" \(50\): entry has ID 51, dropped=true`,
+		},
+		{
+			name: "draining name",
+			schema: descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc",
+				DrainingNames: []descpb.NameInfo{{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "old"}},
+			},
+			parent: makeDB(map[string]descpb.DatabaseDescriptor_SchemaInfo{
+				"sc": {ID: 51}, "old": {ID: 51, Dropped: true},
+			}),
+		},
+		{
+			name:   "stale dropped entry",
+			schema: sc,
+			parent: makeDB(map[string]descpb.DatabaseDescriptor_SchemaInfo{
+				"sc": {ID: 51}, "old": {ID: 51, Dropped: true},
+			}),
+			err: `parent database "db" \(50\) has stale entry "old" \(dropped=true\) for schema "sc" \(51\) in state PUBLIC with 0 draining names`,
+		},
+		{
+			name:   "extra live entry",
+			schema: sc,
+			parent: makeDB(map[string]descpb.DatabaseDescriptor_SchemaInfo{
+				"sc": {ID: 51}, "alias": {ID: 51},
+			}),
+			err: `parent database "db" \(50\) has stale entry "alias" \(dropped=false\) for schema "sc" \(51\)`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema := schemadesc.NewImmutable(tc.schema)
+			descs := catalog.MapDescGetter{schema.GetID(): schema}
+			if tc.parent != nil {
+				descs[tc.parent.GetID()] = tc.parent
+			}
+			var errs catalog.ValidationErrors
+			schema.ValidateCrossReferences(ctx, descs, anyRoleExists{}, &errs)
+			if tc.err == "" {
+				require.NoError(t, errs.CombinedError())
+			} else {
+				require.Regexp(t, tc.err, errs.CombinedError())
+			}
+		})
+	}
+}
+
+func TestDebugSummary(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 3,
+		Privileges:    descpb.NewDefaultPrivilegeDescriptor("alice"),
+		DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+	})
+	require.Equal(t,
+		"user-defined schema 50.sc (51): owner=alice state=PUBLIC version=3 draining_names=1",
+		sc.DebugSummary())
+	require.Equal(t,
+		"user-defined schema 50.‹×› (51): owner=‹×› state=PUBLIC version=3 draining_names=1",
+		string(redact.Sprint(sc).Redact()))
+}
+
+func TestTemporarySessionID(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name string
+		id   uint128.Uint128
+		ok   bool
+	}{
+		{name: "pg_temp_1_2", id: uint128.Uint128{Hi: 1, Lo: 2}, ok: true},
+		{name: "pg_temp_18446744073709551615_0", id: uint128.Uint128{Hi: 1<<64 - 1}, ok: true},
+		{name: "pg_temp_1"},
+		{name: "pg_temp_1_2_3"},
+		{name: "pg_temp_x_2"},
+		{name: "pg_temp_1_-2"},
+		{name: "sc"},
+	} {
+		sc := schemadesc.NewTemporarySchema(tc.name, 51, 50)
+		if tc.name == "sc" {
+			sc = schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: tc.name})
+		}
+		id, ok := sc.TemporarySessionID()
+		require.Equal(t, tc.ok, ok, tc.name)
+		require.Equal(t, tc.id, id, tc.name)
+
+		var errs catalog.ValidationErrors
+		sc.ValidateSelf(&errs)
+		if ok || tc.name == "sc" {
+			require.NoError(t, errs.CombinedError())
+		} else {
+			require.Regexp(t, `temporary schema ".*" \(51\) does not encode a valid session ID`,
+				errs.CombinedError())
+		}
+	}
+}
+
+func TestDDLLocked(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.NoError(t, schemadesc.CheckDDLAllowed(sc))
+	sc.SetDDLLocked(true)
+	require.True(t, sc.IsDDLLocked())
+	require.False(t, sc.ClusterVersion.IsDDLLocked())
+	require.Regexp(t, `schema "sc" is locked for maintenance`, schemadesc.CheckDDLAllowed(sc))
+
+	var errs catalog.ValidationErrors
+	sc.ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	public := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: keys.PublicSchemaID, ParentID: 50, Name: "public", DDLLocked: true,
+	})
+	public.ValidateSelf(&errs)
+	require.Regexp(t, `schema "public" \(29\) cannot be locked against DDL`, errs.CombinedError())
+}
+
+func TestCanCreateObjects(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	db := dbdesc.NewInitial(50, "db", "dbowner")
+	db.Privileges.Grant("dbcreator", privilege.List{privilege.CREATE})
+	scPrivs := descpb.NewDefaultPrivilegeDescriptor("scowner")
+	scPrivs.Grant("sccreator", privilege.List{privilege.CREATE})
+	scPrivs.Grant("reader", privilege.List{privilege.USAGE})
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Privileges: scPrivs,
+	})
+	public := schemadesc.NewImmutable(schemadesc.MakeDefaultPublicSchemaForDatabase(db.DatabaseDesc(), 52))
+
+	for _, tc := range []struct {
+		schema  *schemadesc.Immutable
+		user    string
+		isAdmin bool
+		ok      bool
+	}{
+		{schema: sc, user: "anyone", isAdmin: true, ok: true},
+		{schema: sc, user: "scowner", ok: true},
+		{schema: sc, user: "sccreator", ok: true},
+		{schema: sc, user: "reader"},
+		{schema: sc, user: "dbcreator", ok: true},
+		{schema: sc, user: "dbowner", ok: true},
+		{schema: sc, user: "nobody"},
+		{schema: public, user: "dbcreator", ok: true},
+		{schema: public, user: "dbowner", ok: true},
+		{schema: public, user: "sccreator"},
+	} {
+		require.Equal(t, tc.ok, tc.schema.CanCreateObjects(tc.user, db, tc.isAdmin),
+			"%s on %s", tc.user, tc.schema.GetName())
+	}
+
+	scPrivs.Grant(security.PublicRole, privilege.List{privilege.CREATE})
+	require.True(t, sc.CanCreateObjects("reader", db, false))
+}
+
+// roleMemberships implements catalog.RoleMembershipChecker for tests. It maps
+// each member to all the roles it is a direct or indirect member of.
+type roleMemberships map[string][]string
+
+func (m roleMemberships) MemberOfWithAdminOption(
+	_ context.Context, member string,
+) (map[string]bool, error) {
+	ret := make(map[string]bool)
+	for _, role := range m[member] {
+		ret[role] = false
+	}
+	return ret, nil
+}
+
+func TestValidateOwnerChange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Privileges: descpb.NewDefaultPrivilegeDescriptor("owner"),
+	})
+	membership := roleMemberships{
+		"owner":       {"target"},
+		"owner_child": {"owner", "target"},
+		"outsider":    {"target"},
+		"admin_child": {security.AdminRole},
+	}
+	for _, tc := range []struct {
+		user, newOwner string
+		err            string
+	}{
+		{user: "owner", newOwner: "target"},
+		{user: "owner", newOwner: "owner"},
+		{user: "owner_child", newOwner: "target"},
+		{user: "owner_child", newOwner: "owner_child"},
+		{user: security.RootUser, newOwner: "other"},
+		{user: security.AdminRole, newOwner: "other"},
+		{user: "admin_child", newOwner: "other"},
+		{user: "owner", newOwner: "other", err: `must be member of role "other"`},
+		{user: "outsider", newOwner: "target", err: `must be owner of schema sc`},
+		{user: "outsider", newOwner: "outsider", err: `must be owner of schema sc`},
+	} {
+		err := sc.ValidateOwnerChange(context.Background(), tc.user, tc.newOwner, membership)
+		if tc.err == "" {
+			require.NoError(t, err, "%s to %s", tc.user, tc.newOwner)
+		} else {
+			require.Regexp(t, tc.err, err, "%s to %s", tc.user, tc.newOwner)
+			require.Equal(t, pgcode.InsufficientPrivilege, pgerror.GetPGCode(err))
+		}
+	}
+
+	// A schema created before owners were introduced is owned by the admin
+	// role.
+	legacy := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Privileges: descpb.NewDefaultPrivilegeDescriptor(""),
+	})
+	require.Equal(t, security.AdminRole, legacy.GetOwner())
+	require.NoError(t, legacy.ValidateOwnerChange(context.Background(), "admin_child", "target", membership))
+	require.Regexp(t, `must be owner of schema sc`,
+		legacy.ValidateOwnerChange(context.Background(), "outsider", "target", membership))
+}
+
+func TestValidateRenameBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	schemas := []catalog.SchemaDescriptor{
+		schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "a"}),
+		schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 52, ParentID: 50, Name: "b"}),
+		schemadesc.NewImmutable(descpb.SchemaDescriptor{
+			ID: 53, ParentID: 50, Name: "c",
+			DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old_c"}},
+		}),
+		schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"}),
+	}
+	for _, tc := range []struct {
+		renames map[descpb.ID]string
+		err     string
+	}{
+		{renames: map[descpb.ID]string{51: "x", 52: "y"}},
+		{renames: map[descpb.ID]string{51: "a", 52: "y"}},
+		{renames: map[descpb.ID]string{51: "x", 52: "x"}, err: `schemas 51 and 52 cannot both be renamed to "x"`},
+		{renames: map[descpb.ID]string{51: "c"}, err: `cannot rename schema "a" to "c": name is in use by schema 53`},
+		{renames: map[descpb.ID]string{51: "old_c"}, err: `cannot rename schema "a" to "old_c": name is in use by schema 53`},
+		// Swapping names requires an intermediate step.
+		{renames: map[descpb.ID]string{51: "b", 52: "a"}, err: `cannot rename schema "a" to "b": name is in use by schema 52`},
+		{renames: map[descpb.ID]string{53: "old_c"}, err: `cannot rename schema "c" to "old_c": name is in use by schema 53`},
+		{renames: map[descpb.ID]string{51: "pg_x"}, err: `unacceptable schema name "pg_x"`},
+		{renames: map[descpb.ID]string{keys.PublicSchemaID: "x"}, err: `cannot rename schema "public"`},
+		{renames: map[descpb.ID]string{60: "x"}, err: `schema 60 to be renamed to "x" was not provided`},
+	} {
+		err := schemadesc.ValidateRenameBatch(tc.renames, schemas)
+		if tc.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.Regexp(t, tc.err, err)
+		}
+	}
+}
+
+func TestReferencingViews(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	const dbID, scID = 50, 51
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"})
+	getter := catalog.MapDescGetter{}
+	for _, tbl := range []descpb.TableDescriptor{
+		// Table in the schema, referenced by a view in the schema, a view in the
+		// public schema and a table (through a sequence default expression).
+		{
+			ID: 52, Name: "t", ParentID: dbID, UnexposedParentSchemaID: scID,
+			DependedOnBy: []descpb.TableDescriptor_Reference{{ID: 54}, {ID: 53}, {ID: 55}},
+		},
+		{
+			ID: 53, Name: "v", ParentID: dbID, UnexposedParentSchemaID: scID, ViewQuery: "SELECT 1",
+			DependedOnBy: []descpb.TableDescriptor_Reference{{ID: 54}},
+		},
+		{ID: 54, Name: "v2", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID, ViewQuery: "SELECT 1"},
+		{ID: 55, Name: "t2", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID},
+		// View outside of the schema depending on a table outside of the schema.
+		{
+			ID: 56, Name: "t3", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID,
+			DependedOnBy: []descpb.TableDescriptor_Reference{{ID: 57}},
+		},
+		{ID: 57, Name: "v3", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID, ViewQuery: "SELECT 1"},
+	} {
+		getter[tbl.ID] = tabledesc.NewImmutable(tbl)
+	}
+	views, err := sc.ReferencingViews(ctx, getter)
+	require.NoError(t, err)
+	require.Equal(t, []descpb.ID{53, 54}, views)
+}
+
+func TestMeteringWeight(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc   descpb.SchemaDescriptor
+		weight int64
+	}{
+		{desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}, weight: 1},
+		{desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_temp_1_1"}, weight: 1},
+		{desc: descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"}, weight: 0},
+		{desc: descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_catalog"}, weight: 0},
+	} {
+		require.Equal(t, tc.weight, schemadesc.NewImmutable(tc.desc).MeteringWeight(), tc.desc.Name)
+	}
+}
+
+func TestRemoveStaleDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	drain := func(name string) descpb.NameInfo {
+		return descpb.NameInfo{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: name}
+	}
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		DrainingNames: []descpb.NameInfo{drain("a"), drain("b"), drain("c")},
+	})
+	getter := namespaceOverrideGetter{namespace: map[string]descpb.ID{
+		"sc": 51,
+		"a":  51,
+		// The name "b" was reused by another schema after being drained.
+		"b": 52,
+	}}
+	removed, err := sc.RemoveStaleDrainingNames(ctx, getter)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+	require.Equal(t, []descpb.NameInfo{drain("a")}, sc.DrainingNames)
+	require.Len(t, sc.ClusterVersion.DrainingNames, 3)
+
+	removed, err = sc.RemoveStaleDrainingNames(ctx, getter)
+	require.NoError(t, err)
+	require.Zero(t, removed)
+	require.Equal(t, []descpb.NameInfo{drain("a")}, sc.DrainingNames)
+}
+
+func TestGetDrainingNamesCopy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	drain := func(name string) descpb.NameInfo {
+		return descpb.NameInfo{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: name}
+	}
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		DrainingNames: []descpb.NameInfo{drain("a"), drain("b")},
+	})
+	names := sc.GetDrainingNames()
+	require.Equal(t, []descpb.NameInfo{drain("a"), drain("b")}, names)
+	names[0].Name = "corrupted"
+	_ = append(names[:1], drain("c"))
+	require.Equal(t, []descpb.NameInfo{drain("a"), drain("b")}, sc.GetDrainingNames())
+
+	require.Nil(t, schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}).GetDrainingNames())
+}
+
+func TestRemoveDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	drain := func(name string) descpb.NameInfo {
+		return descpb.NameInfo{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: name}
+	}
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		DrainingNames: []descpb.NameInfo{drain("a"), drain("b"), drain("c")},
+	})
+
+	// Names which are not draining are ignored.
+	other := descpb.NameInfo{ParentID: 49, ParentSchemaID: keys.RootNamespaceID, Name: "b"}
+	require.Zero(t, sc.RemoveDrainingNames([]descpb.NameInfo{drain("d"), other}))
+	require.Zero(t, sc.RemoveDrainingNames(nil))
+	require.Equal(t, []descpb.NameInfo{drain("a"), drain("b"), drain("c")}, sc.DrainingNames)
+
+	require.Equal(t, 2, sc.RemoveDrainingNames([]descpb.NameInfo{drain("c"), drain("a"), drain("d")}))
+	require.Equal(t, []descpb.NameInfo{drain("b")}, sc.DrainingNames)
+
+	require.Equal(t, 1, sc.RemoveDrainingNames([]descpb.NameInfo{drain("b")}))
+	require.Empty(t, sc.DrainingNames)
+	require.Equal(t, []descpb.NameInfo{drain("a"), drain("b"), drain("c")}, sc.ClusterVersion.DrainingNames)
+}
+
+func TestAllowsTypes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	mk := func(id descpb.ID, name string) *schemadesc.Immutable {
+		return schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: id, ParentID: 50, Name: name})
+	}
+	tmp := schemadesc.NewTemporarySchema("pg_temp_1_1", 51, 50)
+	require.True(t, mk(51, "sc").AllowsTypes())
+	require.True(t, mk(keys.PublicSchemaID, "public").AllowsTypes())
+	require.False(t, mk(51, "information_schema").AllowsTypes())
+	require.False(t, tmp.AllowsTypes())
+
+	func() {
+		defer schemadesc.TestingAllowTypesInTemporarySchemas()()
+		require.True(t, tmp.AllowsTypes())
+		require.False(t, mk(51, "information_schema").AllowsTypes())
+	}()
+	require.False(t, tmp.AllowsTypes())
+}
+
+func TestDiffSchemaSets(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	mk := func(id descpb.ID, name string) catalog.SchemaDescriptor {
+		return schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: id, ParentID: 50, Name: name})
+	}
+	intended := []catalog.SchemaDescriptor{mk(54, "d"), mk(51, "a"), mk(52, "b"), mk(53, "c")}
+	actual := []catalog.SchemaDescriptor{mk(56, "f"), mk(51, "a"), mk(53, "renamed"), mk(55, "e")}
+	missing, extra, renamed := schemadesc.DiffSchemaSets(intended, actual)
+	require.Equal(t, []descpb.ID{52, 54}, missing)
+	require.Equal(t, []descpb.ID{55, 56}, extra)
+	require.Equal(t, []descpb.ID{53}, renamed)
+
+	missing, extra, renamed = schemadesc.DiffSchemaSets(intended, intended)
+	require.Empty(t, missing)
+	require.Empty(t, extra)
+	require.Empty(t, renamed)
+}
+
+func TestDiff(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 3,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+		DrainingNames: []descpb.NameInfo{
+			{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "a"},
+		},
+	}
+	orig := schemadesc.NewImmutable(desc)
+
+	// A descriptor has no differences with an equal one.
+	require.Equal(t, []string{}, orig.Diff(schemadesc.NewMutableExisting(desc)))
+
+	mut := schemadesc.NewMutableExisting(desc)
+	require.NoError(t, mut.SetName("renamed"))
+	require.Equal(t, []string{
+		"name: sc -> renamed",
+		"draining name added: sc",
+	}, orig.Diff(mut))
+
+	mut = schemadesc.NewMutableExisting(desc)
+	mut.MaybeIncrementVersion()
+	require.Equal(t, []string{"version: 3 -> 4"}, orig.Diff(mut))
+
+	mut = schemadesc.NewMutableExisting(desc)
+	mut.SetOwner("bob")
+	require.Equal(t, []string{"owner: alice -> bob"}, orig.Diff(mut))
+	mut.Privileges.Grant("carol", privilege.List{privilege.USAGE})
+	mut.DrainingNames = nil
+	require.Equal(t, []string{
+		"owner: alice -> bob",
+		"privileges: changed",
+		"draining name removed: a",
+	}, orig.Diff(mut))
+	require.False(t, orig.Equal(mut))
+
+	comment := "hello"
+	changed := desc
+	changed.OfflineReason = "restoring"
+	changed.ModificationTime = hlc.Timestamp{WallTime: 1}
+	changed.RegionAffinityEnumID = 60
+	changed.ConvertedFromDatabaseID = 52
+	changed.DisableAutoStats = true
+	changed.Comment = &comment
+	changed.DDLLocked = true
+	changed.DefaultTableTTL = time.Hour
+	changed.DefaultPrivileges = &descpb.DefaultPrivilegeDescriptor{
+		Objects: []descpb.DefaultPrivilegesForObject{{
+			ObjectType: "table",
+			Users:      []descpb.UserPrivileges{{User: "carol", Privileges: privilege.SELECT.Mask()}},
+		}},
+	}
+	require.Equal(t, []string{
+		`offline reason: "" -> "restoring"`,
+		"modification time: 0,0 -> 0.000000001,0",
+		"region affinity enum id: 0 -> 60",
+		"converted from database id: 0 -> 52",
+		"disable auto stats: false -> true",
+		`comment: <none> -> "hello"`,
+		"ddl locked: false -> true",
+		"default table ttl: 0s -> 1h0m0s",
+		"default privileges: changed",
+	}, orig.Diff(schemadesc.NewImmutable(changed)))
+
+	require.Equal(t, []string{"other: not a schema descriptor (<nil>)"}, orig.Diff(nil))
+}
+
+func TestSearchPathRank(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const tempID = 60
+	for _, tc := range []struct {
+		name  string
+		sd    sessiondata.SessionData
+		ranks map[string]int
+	}{
+		{
+			// The effective search path is:
+			//   pg_temp_1_1, pg_catalog, alice, sc, pg_extension, public
+			name: "implicit pg_temp",
+			sd: sessiondata.SessionData{
+				User:              "alice",
+				TemporarySchemaID: tempID,
+				SearchPath: sessiondata.MakeSearchPath([]string{"$user", "sc", "public"}).
+					WithTemporarySchemaName("pg_temp_1_1"),
+			},
+			ranks: map[string]int{
+				"pg_temp_1_1": 0, "pg_catalog": 1, "alice": 2, "sc": 3, "pg_extension": 4, "public": 5,
+				"other": -1, "$user": -1, "pg_temp": -1,
+			},
+		},
+		{
+			// The effective search path is:
+			//   pg_catalog, sc, pg_temp_1_1, pg_extension, public
+			name: "explicit pg_temp",
+			sd: sessiondata.SessionData{
+				User:              "alice",
+				TemporarySchemaID: tempID,
+				SearchPath: sessiondata.MakeSearchPath([]string{"sc", "pg_temp", "public"}).
+					WithTemporarySchemaName("pg_temp_1_1"),
+			},
+			ranks: map[string]int{
+				"pg_catalog": 0, "sc": 1, "pg_temp_1_1": 2, "pg_extension": 3, "public": 4,
+				"alice": -1, "pg_temp": -1,
+			},
+		},
+		{
+			// Without a temporary schema, pg_temp is skipped:
+			//   pg_catalog, sc, pg_extension, public
+			name: "no temporary schema",
+			sd: sessiondata.SessionData{
+				User:       "alice",
+				SearchPath: sessiondata.MakeSearchPath([]string{"sc", "pg_temp", "public"}),
+			},
+			ranks: map[string]int{
+				"pg_catalog": 0, "sc": 1, "pg_extension": 2, "public": 3,
+				"pg_temp": -1, "pg_temp_1_1": -1,
+			},
+		},
+		{
+			// Without a session user, $user is skipped:
+			//   pg_catalog, sc, pg_extension, public
+			name: "no session user",
+			sd: sessiondata.SessionData{
+				SearchPath: sessiondata.MakeSearchPath([]string{"$user", "sc", "public"}),
+			},
+			ranks: map[string]int{
+				"pg_catalog": 0, "sc": 1, "pg_extension": 2, "public": 3, "$user": -1, "": -1,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, expected := range tc.ranks {
+				sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: name})
+				if strings.HasPrefix(name, "pg_temp_") {
+					sc = schemadesc.NewTemporarySchema(name, tempID, 50)
+				}
+				rank, ok := sc.SearchPathRank(&tc.sd)
+				require.Equal(t, expected >= 0, ok, name)
+				require.Equal(t, expected, rank, name)
+			}
+		})
+	}
+
+	// A schema with the name of the session's temporary schema but another ID
+	// is not on the search path.
+	sd := &sessiondata.SessionData{
+		TemporarySchemaID: tempID,
+		SearchPath:        sessiondata.MakeSearchPath([]string{"public"}).WithTemporarySchemaName("pg_temp_1_1"),
+	}
+	rank, ok := schemadesc.NewTemporarySchema("pg_temp_1_1", tempID+1, 50).SearchPathRank(sd)
+	require.False(t, ok)
+	require.Equal(t, -1, rank)
+}
+
+func TestWithoutDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		Privileges:    descpb.NewDefaultPrivilegeDescriptor("alice"),
+		DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+	})
+	clone := sc.WithoutDrainingNames()
+	require.Empty(t, clone.DrainingNames)
+	require.Len(t, sc.DrainingNames, 1)
+
+	clone.Privileges.SetOwner("bob")
+	require.Equal(t, "alice", sc.Privileges.Owner)
+
+	other := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc",
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+	})
+	require.Equal(t, other.SchemaDescriptor, sc.WithoutDrainingNames().SchemaDescriptor)
+}
+
+func TestValidateSelfPublicOwner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, name := range []string{"sc", "public"} {
+		sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: name,
+			Privileges: descpb.NewDefaultPrivilegeDescriptor(security.PublicRole),
+		})
+		var errs catalog.ValidationErrors
+		sc.ValidateSelf(&errs)
+		err := errs.CombinedError()
+		require.Regexp(t, `schema ".*" \(51\) is owned by the "public" role, which cannot own objects`, err)
+		require.Contains(t, errors.FlattenHints(err), "REASSIGN OWNED")
+	}
+}
+
+func TestAllNamespaceKeysToDelete(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID:       51,
+		ParentID: 50,
+		Name:     "c",
+		DrainingNames: []descpb.NameInfo{
+			{ParentID: 50, Name: "a"},
+			{ParentID: 50, Name: "b"},
+			{ParentID: 50, Name: "a"},
+		},
+	})
+	for _, codec := range []keys.SQLCodec{
+		keys.SystemSQLCodec,
+		keys.MakeSQLCodec(roachpb.MakeTenantID(10)),
+	} {
+		require.Equal(t, []roachpb.Key{
+			catalogkeys.NewSchemaKey(50, "c").Key(codec),
+			catalogkeys.NewSchemaKey(50, "a").Key(codec),
+			catalogkeys.NewSchemaKey(50, "b").Key(codec),
+		}, desc.AllNamespaceKeysToDelete(codec))
+	}
+}
+
+func TestDescriptorKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.Equal(t, keys.SystemSQLCodec.DescMetadataKey(51), desc.DescriptorKey(keys.SystemSQLCodec))
+
+	tenantCodec := keys.MakeSQLCodec(roachpb.MakeTenantID(10))
+	tenantKey := desc.DescriptorKey(tenantCodec)
+	require.Equal(t, tenantCodec.DescMetadataKey(51), tenantKey)
+	require.True(t, bytes.HasPrefix(tenantKey, keys.MakeTenantPrefix(roachpb.MakeTenantID(10))))
+}
+
+func TestAllowedLeaseVersions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc     descpb.SchemaDescriptor
+		prior    descpb.DescriptorVersion
+		hasPrior bool
+	}{
+		{desc: descpb.SchemaDescriptor{Version: 1}},
+		{desc: descpb.SchemaDescriptor{Version: 3}},
+		{
+			desc: descpb.SchemaDescriptor{
+				Version: 3, DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+			},
+			prior:    2,
+			hasPrior: true,
+		},
+		{
+			desc:     descpb.SchemaDescriptor{Version: 3, State: descpb.SchemaDescriptor_DROP},
+			prior:    2,
+			hasPrior: true,
+		},
+		{
+			desc: descpb.SchemaDescriptor{
+				Version: 1, DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+			},
+		},
+	} {
+		tc.desc.ID, tc.desc.ParentID, tc.desc.Name = 51, 50, "sc"
+		current, prior, hasPrior := schemadesc.NewImmutable(tc.desc).AllowedLeaseVersions()
+		require.Equal(t, tc.desc.Version, current)
+		require.Equal(t, tc.prior, prior)
+		require.Equal(t, tc.hasPrior, hasPrior)
+	}
+}
+
+func TestValidateDatabaseToSchemaConversion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	db := dbdesc.NewInitial(50, "db", security.AdminRole)
+	db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 51}}
+	newSchema := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 61, ParentID: 60, Name: "db", ConvertedFromDatabaseID: 50,
+	})
+	table := func(id, parentID, parentSchemaID descpb.ID) catalog.Descriptor {
+		return tabledesc.NewImmutable(descpb.TableDescriptor{
+			ID: id, Name: "t", ParentID: parentID, UnexposedParentSchemaID: parentSchemaID,
+		})
+	}
+
+	getter := catalog.MapDescGetter{70: table(70, 60, 61), 71: table(71, 60, 61)}
+	require.NoError(t, schemadesc.ValidateDatabaseToSchemaConversion(ctx, db, newSchema, getter))
+
+	getter[72] = table(72, 50, keys.PublicSchemaID)
+	require.Regexp(t, `object 72 is still in schema 29 of converted database "db" \(50\)`,
+		schemadesc.ValidateDatabaseToSchemaConversion(ctx, db, newSchema, getter))
+	delete(getter, 72)
+	getter[73] = table(73, 50, 51)
+	require.Regexp(t, `object 73 is still in schema 51 of converted database "db" \(50\)`,
+		schemadesc.ValidateDatabaseToSchemaConversion(ctx, db, newSchema, getter))
+
+	unrecorded := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 61, ParentID: 60, Name: "db"})
+	require.Regexp(t, `schema "db" \(61\) is not recorded as converted from database "db" \(50\)`,
+		schemadesc.ValidateDatabaseToSchemaConversion(ctx, db, unrecorded, catalog.MapDescGetter{}))
+}
+
+func TestValidateSelfDefaultTableTTL(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name string
+		ttl  time.Duration
+		err  string
+	}{
+		{name: "sc"},
+		{name: "sc", ttl: time.Hour},
+		{name: "sc", ttl: -time.Hour, err: `schema "sc" \(51\) has negative default table TTL -1h0m0s`},
+		{name: "public", ttl: time.Hour, err: `schema "public" \(51\) cannot have a default table TTL`},
+	} {
+		sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: tc.name, DefaultTableTTL: tc.ttl,
+		})
+		require.Equal(t, tc.ttl, sc.GetDefaultTableTTL())
+		var errs catalog.ValidationErrors
+		sc.ValidateSelf(&errs)
+		if tc.err == "" {
+			require.NoError(t, errs.CombinedError())
+		} else {
+			require.Regexp(t, tc.err, errs.CombinedError())
+		}
+	}
+}
+
+func TestRenameAffectsMaterializedViews(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	const dbID, scID = 50, 51
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: scID, ParentID: dbID, Name: "sc"})
+	getter := catalog.MapDescGetter{}
+	for _, tbl := range []descpb.TableDescriptor{
+		{
+			ID: 52, Name: "t", ParentID: dbID, UnexposedParentSchemaID: scID,
+			DependedOnBy: []descpb.TableDescriptor_Reference{{ID: 54}, {ID: 55}},
+		},
+		// A materialized view depending only on a type in the schema.
+		{ID: 53, Name: "mv_typ", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID, ViewQuery: "SELECT 1", IsMaterializedView: true},
+		{ID: 54, Name: "mv", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID, ViewQuery: "SELECT 1", IsMaterializedView: true},
+		{ID: 55, Name: "v", ParentID: dbID, UnexposedParentSchemaID: keys.PublicSchemaID, ViewQuery: "SELECT 1"},
+	} {
+		getter[tbl.ID] = tabledesc.NewImmutable(tbl)
+	}
+	getter[56] = typedesc.NewImmutable(descpb.TypeDescriptor{
+		ID: 56, Name: "typ", ParentID: dbID, ParentSchemaID: scID,
+		ReferencingDescriptorIDs: []descpb.ID{53},
+	})
+
+	affected, err := sc.RenameAffectsMaterializedViews(ctx, getter)
+	require.NoError(t, err)
+	require.Equal(t, []descpb.ID{54}, affected)
+
+	views, err := sc.ReferencingViews(ctx, getter)
+	require.NoError(t, err)
+	require.Equal(t, []descpb.ID{53, 54, 55}, views)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	privs := descpb.NewDefaultPrivilegeDescriptor("alice")
+	privs.Grant("carol", privilege.List{privilege.USAGE, privilege.CREATE})
+	privs.Grant("bob", privilege.List{privilege.USAGE})
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 4, Privileges: privs,
+	})
+	out, err := sc.ToYAML()
+	require.NoError(t, err)
+	require.Equal(t, `name: sc
+owner: alice
+privileges:
+- user: admin
+  privileges: [ALL]
+- user: bob
+  privileges: [USAGE]
+- user: carol
+  privileges: [CREATE, USAGE]
+- user: root
+  privileges: [ALL]
+`, string(out))
+
+	parsed, err := schemadesc.FromYAML(out)
+	require.NoError(t, err)
+	require.True(t, parsed.IsNew())
+	require.Equal(t, "sc", parsed.Name)
+	require.Equal(t, privs, parsed.Privileges)
+	again, err := parsed.ToYAML()
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again))
+
+	// Legacy descriptors without privileges or without an owner are
+	// reported as owned by the admin role, and can be parsed back.
+	legacyPrivs := descpb.NewDefaultPrivilegeDescriptor("alice")
+	legacyPrivs.Owner = ""
+	for _, privs := range []*descpb.PrivilegeDescriptor{nil, legacyPrivs} {
+		legacy := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: "sc", Privileges: privs,
+		})
+		out, err := legacy.ToYAML()
+		require.NoError(t, err)
+		require.Equal(t, `name: sc
+owner: admin
+privileges:
+- user: admin
+  privileges: [ALL]
+- user: root
+  privileges: [ALL]
+`, string(out))
+		parsed, err := schemadesc.FromYAML(out)
+		require.NoError(t, err)
+		require.Equal(t, security.AdminRole, parsed.GetOwner())
+	}
+
+	for in, expected := range map[string]string{
+		"name: sc\nowner: alice\nlabels: {a: b}\n":              `field labels not found`,
+		"name: pg_sc\nowner: alice\n":                           `unacceptable schema name "pg_sc"`,
+		"name: \"\"\nowner: alice\n":                            `empty schema name`,
+		"owner: alice\n":                                        `empty schema name`,
+		"name: " + strings.Repeat("a", 64) + "\nowner: alice\n": `schema name is 64 bytes long`,
+		"name: Public\nowner: alice\n":                          `unacceptable schema name "Public"`,
+		"name: sc\n":                                            `schema "sc" has no owner`,
+		"name: sc\nowner: alice\nprivileges: [{user: bob, privileges: [FOO]}]\n": `not a valid privilege: "FOO"`,
+	} {
+		_, err := schemadesc.FromYAML([]byte(in))
+		require.Regexp(t, expected, err, in)
+	}
+}
+
+func TestOIDCollision(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// The OIDs are visible to clients and must remain stable.
+	require.Equal(t, uint32(1330834471), schemadesc.NamespaceOID(50, "public"))
+	require.Equal(t, uint32(274262934), schemadesc.NamespaceOID(50, "sc"))
+	require.Equal(t, uint32(274410223), schemadesc.NamespaceOID(51, "sc"))
+
+	mk := func(id, parentID descpb.ID, name string) catalog.SchemaDescriptor {
+		return schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: id, ParentID: parentID, Name: name})
+	}
+	a := mk(52, 50, "sc")
+	require.False(t, schemadesc.OIDCollision(a, a))
+	require.False(t, schemadesc.OIDCollision(a, mk(53, 51, "sc")))
+	require.False(t, schemadesc.OIDCollision(a, mk(53, 50, "other")))
+	// Two descriptors claiming the same name in the same database collide.
+	require.True(t, schemadesc.OIDCollision(a, mk(53, 50, "sc")))
+}
+
+func TestDropLeaseWaitDeadline(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", State: descpb.SchemaDescriptor_DROP,
+	})
+	modTime := hlc.Timestamp{WallTime: 100, Logical: 3}
+	require.Equal(t,
+		hlc.Timestamp{WallTime: 100 + int64(5*time.Minute), Logical: 3},
+		sc.DropLeaseWaitDeadline(modTime, 5*time.Minute))
+	require.Equal(t, modTime, sc.DropLeaseWaitDeadline(modTime, 0))
+}
+
+func TestBackupManifestEntry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 3,
+		Privileges:    descpb.NewDefaultPrivilegeDescriptor("owner"),
+		DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+	})
+	desc := sc.BackupManifestEntry()
+	entry := desc.GetSchema()
+	require.NotNil(t, entry)
+	require.Empty(t, entry.DrainingNames)
+	require.Equal(t, descpb.ID(51), entry.ID)
+	require.Equal(t, descpb.ID(50), entry.ParentID)
+	require.Equal(t, "sc", entry.Name)
+	require.Equal(t, "owner", entry.Privileges.Owner)
+	require.Equal(t, sc.Privileges, entry.Privileges)
+	// The entry must not share state with the descriptor.
+	require.NotSame(t, sc.Privileges, entry.Privileges)
+	require.Len(t, sc.DrainingNames, 1)
+}
+
+func TestOwnerAccessors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// A descriptor without privileges is owned by admin.
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.NotNil(t, sc.GetPrivileges())
+	require.Equal(t, security.AdminRole, sc.GetPrivileges().Owner)
+	require.Equal(t, security.AdminRole, sc.GetOwner())
+	require.Nil(t, sc.Privileges)
+
+	// A descriptor predating owners is owned by admin.
+	sc = schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Privileges: &descpb.PrivilegeDescriptor{},
+	})
+	require.Equal(t, security.AdminRole, sc.GetOwner())
+
+	mut := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+	})
+	require.Equal(t, "alice", mut.GetOwner())
+	mut.SetOwner("bob")
+	require.Equal(t, "bob", mut.GetOwner())
+	require.Equal(t, "bob", mut.GetPrivileges().Owner)
+	require.Equal(t, "alice", mut.ClusterVersion.GetOwner())
+
+	mut = schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	mut.SetOwner("bob")
+	require.Equal(t, "bob", mut.GetOwner())
+	require.Equal(t, security.AdminRole, mut.ClusterVersion.GetOwner())
+}
+
+func TestMutableConstructorsDefaultPrivileges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, mk := range []func(descpb.SchemaDescriptor) *schemadesc.Mutable{
+		schemadesc.NewMutableExisting,
+		schemadesc.NewMutableCreatedSchemaDescriptor,
+	} {
+		// A descriptor without privileges gets the default ones.
+		sc := mk(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+		require.Equal(t, descpb.NewDefaultPrivilegeDescriptor(security.AdminRole), sc.Privileges)
+		var errs catalog.ValidationErrors
+		sc.ValidateSelf(&errs)
+		require.NoError(t, errs.CombinedError())
+
+		// Privileges which are already present are kept.
+		privs := descpb.NewDefaultPrivilegeDescriptor("alice")
+		privs.Grant("bob", privilege.List{privilege.USAGE})
+		sc = mk(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Privileges: privs})
+		require.Equal(t, "alice", sc.GetOwner())
+		require.True(t, sc.Privileges.CheckPrivilege("bob", privilege.USAGE))
+	}
+
+	// Immutable descriptors are not defaulted.
+	require.Nil(t, schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}).Privileges)
+}
+
+func TestSchemaStates(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1,
+	})
+	require.False(t, sc.Adding())
+	require.False(t, sc.Offline())
+	require.False(t, sc.Dropped())
+	require.NoError(t, catalog.FilterDescriptorState(sc))
+
+	sc.SetOffline("restoring")
+	require.True(t, sc.Offline())
+	require.Equal(t, "restoring", sc.GetOfflineReason())
+	require.Regexp(t, `schema "sc" is offline: restoring`, catalog.FilterDescriptorState(sc))
+	require.False(t, sc.ClusterVersion.Offline())
+
+	sc.SetPublic()
+	require.False(t, sc.Offline())
+	require.Empty(t, sc.GetOfflineReason())
+	require.NoError(t, catalog.FilterDescriptorState(sc))
+
+	sc.State = descpb.SchemaDescriptor_ADD
+	require.True(t, sc.Adding())
+	require.False(t, sc.Offline())
+
+	sc.State = descpb.SchemaDescriptor_DROP
+	require.True(t, sc.Dropped())
+	require.True(t, sc.Offline())
+	require.True(t, catalog.HasInactiveDescriptorError(catalog.FilterDescriptorState(sc)))
+}
+
+func TestBuilder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	desc := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 2,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+	}
+
+	b := schemadesc.NewBuilder(&desc)
+	require.Equal(t, catalog.Schema, b.DescriptorType())
+	require.Equal(t, schemadesc.NewImmutable(desc), b.BuildImmutable())
+	require.Equal(t, schemadesc.NewMutableExisting(desc), b.BuildExistingMutable())
+	require.Equal(t, schemadesc.NewMutableCreatedSchemaDescriptor(desc), b.BuildCreatedMutable())
+
+	// Existing mutable descriptors do not share state with the builder.
+	mut := b.BuildExistingMutableSchema()
+	mut.SetOwner("bob")
+	require.Equal(t, "alice", mut.ClusterVersion.GetOwner())
+	require.Equal(t, "alice", b.BuildImmutableSchema().GetOwner())
+	require.Equal(t, "alice", desc.Privileges.Owner)
+
+	// Post-deserialization changes restore the privileges of the superusers,
+	// but not in the cluster version.
+	broken := desc
+	broken.Privileges = descpb.NewDefaultPrivilegeDescriptor("alice")
+	broken.Privileges.Revoke(security.AdminRole, privilege.List{privilege.ALL}, privilege.Schema)
+	b = schemadesc.NewBuilder(&broken)
+	require.NoError(t, b.RunPostDeserializationChanges(ctx, nil /* dg */))
+	require.True(t, b.BuildImmutableSchema().Privileges.CheckPrivilege(security.AdminRole, privilege.ALL))
+	mut = b.BuildExistingMutableSchema()
+	require.True(t, mut.Privileges.CheckPrivilege(security.AdminRole, privilege.ALL))
+	require.False(t, mut.ClusterVersion.Privileges.CheckPrivilege(security.AdminRole, privilege.ALL))
+}
+
+func TestBackupFingerprint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	base := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"),
+	}
+	sc := schemadesc.NewImmutable(base)
+	fp := sc.BackupFingerprint()
+	require.Equal(t, descpb.DescriptorVersion(1), sc.Version)
+
+	// The version, modification time and draining names do not contribute.
+	same := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+	same.Version = 7
+	same.ModificationTime = hlc.Timestamp{WallTime: 100}
+	same.DrainingNames = []descpb.NameInfo{{ParentID: 50, Name: "old"}}
+	require.Equal(t, fp, schemadesc.NewImmutable(same).BackupFingerprint())
+
+	for _, mutate := range []func(*descpb.SchemaDescriptor){
+		func(d *descpb.SchemaDescriptor) { d.ID = 52 },
+		func(d *descpb.SchemaDescriptor) { d.ParentID = 49 },
+		func(d *descpb.SchemaDescriptor) { d.Name = "sc2" },
+		func(d *descpb.SchemaDescriptor) { d.Privileges.SetOwner("bob") },
+		func(d *descpb.SchemaDescriptor) { d.Privileges.Grant("bob", privilege.List{privilege.USAGE}) },
+	} {
+		changed := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+		mutate(&changed)
+		require.NotEqual(t, fp, schemadesc.NewImmutable(changed).BackupFingerprint())
+	}
+}
+
+func TestMaybeIncrementVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	modTime := hlc.Timestamp{WallTime: 100}
+	desc := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 3, ModificationTime: modTime,
+	}
+
+	// The version equals the cluster version: it is incremented once.
+	sc := schemadesc.NewMutableExisting(desc)
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(4), sc.Version)
+	require.Equal(t, hlc.Timestamp{}, sc.ModificationTime)
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(4), sc.Version)
+
+	// The version is already ahead of the cluster version: it is left alone.
+	sc = schemadesc.NewMutableExisting(desc)
+	sc.Version = 5
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(5), sc.Version)
+	require.Equal(t, modTime, sc.ModificationTime)
+
+	// The version is behind the cluster version: it is moved one past it.
+	sc = schemadesc.NewMutableExisting(desc)
+	sc.Version = 1
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(4), sc.Version)
+	require.Equal(t, hlc.Timestamp{}, sc.ModificationTime)
+
+	// A newly created descriptor has no cluster version: it keeps its version,
+	// and its modification time is filled in when it is committed.
+	sc = schemadesc.NewMutableCreatedSchemaDescriptor(desc)
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(3), sc.Version)
+	require.Equal(t, hlc.Timestamp{}, sc.ModificationTime)
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(3), sc.Version)
+}
+
+func TestHasPendingModificationTime(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	modTime := hlc.Timestamp{WallTime: 100}
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 3, ModificationTime: modTime,
+	})
+	require.Equal(t, modTime, sc.GetModificationTime())
+	require.False(t, sc.HasPendingModificationTime())
+
+	sc.MaybeIncrementVersion()
+	require.Equal(t, hlc.Timestamp{}, sc.GetModificationTime())
+	require.True(t, sc.HasPendingModificationTime())
+	require.False(t, sc.ClusterVersion.HasPendingModificationTime())
+
+	// A created descriptor behaves the same once it is incremented.
+	sc = schemadesc.NewMutableCreatedSchemaDescriptor(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1, ModificationTime: modTime,
+	})
+	require.False(t, sc.HasPendingModificationTime())
+	sc.MaybeIncrementVersion()
+	require.Equal(t, descpb.DescriptorVersion(1), sc.GetVersion())
+	require.True(t, sc.HasPendingModificationTime())
+}
+
+func TestIsUncommittedVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Version: 3}
+
+	// A freshly read descriptor matches its cluster version.
+	sc := schemadesc.NewMutableExisting(desc)
+	require.Equal(t, descpb.DescriptorVersion(3), sc.GetVersion())
+	require.False(t, sc.IsUncommittedVersion())
+
+	// A pending increment is ahead of the cluster version.
+	sc.MaybeIncrementVersion()
+	require.True(t, sc.IsUncommittedVersion())
+	require.Equal(t, descpb.DescriptorVersion(3), sc.ClusterVersion.GetVersion())
+
+	// A newly created descriptor has never been written.
+	sc = schemadesc.NewMutableCreatedSchemaDescriptor(desc)
+	require.Nil(t, sc.ClusterVersion)
+	require.True(t, sc.IsUncommittedVersion())
+}
+
+func TestSetNameDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "a"})
+	require.NoError(t, sc.SetName("a"))
+	require.Empty(t, sc.DrainingNames)
+
+	for _, name := range []string{"b", "a", "c"} {
+		require.NoError(t, sc.SetName(name))
+	}
+	require.Equal(t, "c", sc.Name)
+	require.Equal(t, []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "a"},
+	}, sc.DrainingNames)
+}
+
+func TestSetNameBackAndForth(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "a", Version: 1,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+	}
+	sc := schemadesc.NewMutableExisting(desc)
+	require.NoError(t, sc.SetName("b"))
+	require.NoError(t, sc.SetName("a"))
+	require.Equal(t, "a", sc.Name)
+	require.Equal(t, []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+	}, sc.DrainingNames)
+	var errs catalog.ValidationErrors
+	sc.ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	// The draining names of the cluster version are left untouched.
+	desc.DrainingNames = []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+	}
+	sc = schemadesc.NewMutableExisting(desc)
+	require.NoError(t, sc.SetName("b"))
+	require.Equal(t, []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "a"},
+	}, sc.DrainingNames)
+	require.Equal(t, []descpb.NameInfo{
+		{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+	}, sc.ClusterVersion.GetDrainingNames())
+}
+
+func TestIsSchemaNameValid(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, name := range []string{
+		"sales", "public_data", "crdb", strings.Repeat("a", schemadesc.MaxSchemaNameLength),
+	} {
+		require.NoError(t, schemadesc.IsSchemaNameValid(name), name)
+	}
+	for _, name := range []string{
+		"pg_sc", "crdb_internal", "CRDB_Internal", "information_schema", "Information_Schema", "public", "PUBLIC",
+	} {
+		err := schemadesc.IsSchemaNameValid(name)
+		require.Regexp(t, `unacceptable schema name`, err, name)
+		require.Equal(t, pgcode.ReservedName, pgerror.GetPGCode(err), name)
+	}
+	require.Equal(t, `The name "crdb_internal" is reserved for a system schema.`,
+		errors.FlattenDetails(schemadesc.IsSchemaNameValid("Crdb_Internal")))
+
+	for _, tc := range []struct {
+		name string
+		code pgcode.Code
+		err  string
+	}{
+		{
+			name: strings.Repeat("a", schemadesc.MaxSchemaNameLength+1),
+			code: pgcode.NameTooLong,
+			err:  `schema name is 64 bytes long, exceeding the maximum of 63 bytes`,
+		},
+		{name: "", code: pgcode.InvalidName, err: `empty schema name`},
+		{
+			name: "sc\x00x",
+			code: pgcode.InvalidName,
+			err:  `schema name "sc\\x00x" contains invalid control character U\+0000`,
+		},
+	} {
+		err := schemadesc.IsSchemaNameValid(tc.name)
+		require.Regexp(t, tc.err, err)
+		require.Equal(t, tc.code, pgerror.GetPGCode(err))
+	}
+}
+
+func TestLegacySchemaName(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// A schema stored with a name which predates the rules of
+	// IsSchemaNameValid fails ValidateSelf, but can still be read.
+	ctx := context.Background()
+	for _, name := range []string{
+		strings.Repeat("a", schemadesc.MaxSchemaNameLength+1), "sc\tx", "Crdb_Internal", "INFORMATION_SCHEMA", "Public",
+	} {
+		desc := descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: name, Version: 1,
+			Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+		}
+		b := schemadesc.NewBuilder(&desc)
+		require.NoError(t, b.RunPostDeserializationChanges(ctx, nil /* dg */))
+		sc := b.BuildExistingMutableSchema()
+		require.Equal(t, catalog.SchemaUserDefined, sc.GetSchemaKind(), name)
+		var errs catalog.ValidationErrors
+		sc.ValidateSelf(&errs)
+		require.Error(t, errs.CombinedError(), name)
+		errs = nil
+		sc.ValidateIdentity(&errs)
+		require.NoError(t, errs.CombinedError(), name)
+
+		// It can be renamed to a valid name, but not to another legacy one.
+		schemas := []catalog.SchemaDescriptor{sc}
+		require.NoError(t, schemadesc.ValidateRenameBatch(map[descpb.ID]string{51: "renamed"}, schemas))
+		require.Regexp(t, `schema name is 64 bytes long`, schemadesc.ValidateRenameBatch(
+			map[descpb.ID]string{51: strings.Repeat("b", 64)}, schemas))
+	}
+}
+
+func TestIsSchemaNameValidTelemetry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	require.NoError(t, schemadesc.IsSchemaNameValid("sales"))
+
+	for _, tc := range []struct {
+		name   string
+		reason string
+	}{
+		{name: "", reason: "empty"},
+		{name: strings.Repeat("a", schemadesc.MaxSchemaNameLength+1), reason: "too_long"},
+		{name: "sc\x00x", reason: "control_character"},
+		{name: "pg_sc", reason: "pg_prefix"},
+		{name: "CRDB_Internal", reason: "crdb_internal"},
+		{name: "information_schema", reason: "information_schema"},
+		{name: "PUBLIC", reason: "public"},
+	} {
+		err := schemadesc.IsSchemaNameValid(tc.name)
+		require.Error(t, err, tc.name)
+		key := sqltelemetry.InvalidSchemaNameCounterPrefix + tc.reason
+		require.Equal(t, []string{key}, errors.GetTelemetryKeys(err), tc.name)
+
+		// The key is counted once, when the error is recorded.
+		counter := telemetry.GetCounter(
+			fmt.Sprintf("othererror.%s.%s", pgerror.GetPGCode(err).String(), key))
+		before := telemetry.Read(counter)
+		telemetry.RecordError(err)
+		require.Equal(t, before+1, telemetry.Read(counter), tc.name)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	makeDesc := func() descpb.SchemaDescriptor {
+		return descpb.SchemaDescriptor{
+			ID: 51, ParentID: 50, Name: "sc", Version: 2,
+			Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+			DrainingNames: []descpb.NameInfo{
+				{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "a"},
+				{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+			},
+		}
+	}
+	sc := schemadesc.NewImmutable(makeDesc())
+
+	require.True(t, sc.Equal(schemadesc.NewImmutable(makeDesc())))
+	require.True(t, sc.Equal(schemadesc.NewMutableExisting(makeDesc())))
+
+	reordered := makeDesc()
+	reordered.DrainingNames[0], reordered.DrainingNames[1] =
+		reordered.DrainingNames[1], reordered.DrainingNames[0]
+	require.True(t, sc.Equal(schemadesc.NewImmutable(reordered)))
+
+	privs := makeDesc()
+	privs.Privileges.Grant("testuser", privilege.List{privilege.USAGE})
+	require.False(t, sc.Equal(schemadesc.NewImmutable(privs)))
+
+	renamed := makeDesc()
+	renamed.Name = "other"
+	require.False(t, sc.Equal(schemadesc.NewImmutable(renamed)))
+
+	// Every other field of the descriptor is compared too.
+	comment := "hello"
+	for name, mutate := range map[string]func(*descpb.SchemaDescriptor){
+		"modification time": func(d *descpb.SchemaDescriptor) { d.ModificationTime = hlc.Timestamp{WallTime: 1} },
+		"offline reason":    func(d *descpb.SchemaDescriptor) { d.OfflineReason = "restoring" },
+		"region affinity":   func(d *descpb.SchemaDescriptor) { d.RegionAffinityEnumID = 60 },
+		"converted from":    func(d *descpb.SchemaDescriptor) { d.ConvertedFromDatabaseID = 52 },
+		"auto stats":        func(d *descpb.SchemaDescriptor) { d.DisableAutoStats = true },
+		"comment":           func(d *descpb.SchemaDescriptor) { d.Comment = &comment },
+		"ddl locked":        func(d *descpb.SchemaDescriptor) { d.DDLLocked = true },
+		"default table ttl": func(d *descpb.SchemaDescriptor) { d.DefaultTableTTL = time.Hour },
+		"default privileges": func(d *descpb.SchemaDescriptor) {
+			d.DefaultPrivileges = &descpb.DefaultPrivilegeDescriptor{
+				Objects: []descpb.DefaultPrivilegesForObject{{
+					ObjectType: "table",
+					Users:      []descpb.UserPrivileges{{User: "testuser", Privileges: privilege.SELECT.Mask()}},
+				}},
+			}
+		},
+	} {
+		changed := makeDesc()
+		mutate(&changed)
+		require.False(t, sc.Equal(schemadesc.NewImmutable(changed)), name)
+	}
+
+	// Missing privileges and default privileges stand for the ones they imply.
+	implicit := makeDesc()
+	implicit.Privileges = nil
+	explicit := makeDesc()
+	explicit.DefaultPrivileges = &descpb.DefaultPrivilegeDescriptor{}
+	require.True(t, schemadesc.NewImmutable(implicit).Equal(schemadesc.NewImmutable(explicit)))
+
+	require.False(t, sc.Equal(nil))
+	require.False(t, sc.Equal((*schemadesc.Immutable)(nil)))
+	require.False(t, sc.Equal((*schemadesc.Mutable)(nil)))
+}
+
+func TestSafeFormat(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "secret", Version: 7, State: descpb.SchemaDescriptor_OFFLINE,
+	})
+	require.Equal(t,
+		"user-defined schema 50.secret (51): owner=admin state=OFFLINE version=7 draining_names=0",
+		sc.String())
+	require.Equal(t, sc.String(), fmt.Sprint(sc))
+
+	redacted := string(redact.Sprintf("%v", sc).Redact())
+	require.NotContains(t, redacted, "secret")
+	require.Equal(t,
+		"user-defined schema 50.‹×› (51): owner=‹×› state=OFFLINE version=7 draining_names=0",
+		redacted)
+	require.Contains(t, string(errors.Redact(errors.Newf("failed on %s", sc))), "(51)")
+}
+
+func TestForEachDrainingName(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	collect := func(sc *schemadesc.Immutable) []string {
+		var names []string
+		require.NoError(t, sc.ForEachDrainingName(func(ni descpb.NameInfo) error {
+			names = append(names, ni.Name)
+			return nil
+		}))
+		return names
+	}
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.False(t, sc.HasDrainingNames())
+	require.Empty(t, collect(sc))
+
+	sc.DrainingNames = []descpb.NameInfo{{ParentID: 50, Name: "a"}}
+	require.True(t, sc.HasDrainingNames())
+	require.Equal(t, []string{"a"}, collect(sc))
+
+	sc.DrainingNames = append(sc.DrainingNames,
+		descpb.NameInfo{ParentID: 50, Name: "b"}, descpb.NameInfo{ParentID: 50, Name: "c"})
+	require.Equal(t, []string{"a", "b", "c"}, collect(sc))
+
+	var visited []string
+	err := sc.ForEachDrainingName(func(ni descpb.NameInfo) error {
+		visited = append(visited, ni.Name)
+		if ni.Name == "b" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "boom")
+	require.Equal(t, []string{"a", "b"}, visited)
+}
+
+func TestRunPostDeserializationChanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Version: 1})
+	sc.Privileges = nil
+	require.Equal(t, catalog.PostDeserializationChanges{DefaultPrivilegesAdded: true},
+		sc.RunPostDeserializationChanges())
+	require.Equal(t, descpb.NewDefaultPrivilegeDescriptor(security.AdminRole), sc.Privileges)
+	require.Zero(t, sc.RunPostDeserializationChanges())
+	require.Equal(t, catalog.PostDeserializationChanges{DefaultPrivilegesAdded: true},
+		sc.GetPostDeserializationChanges())
+
+	sc = schemadesc.NewMutableExisting(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1, State: descpb.SchemaDescriptor_State(42),
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"), OfflineReason: "restoring",
+	})
+	require.Equal(t, catalog.PostDeserializationChanges{StateNormalized: true, OfflineReasonCleared: true},
+		sc.RunPostDeserializationChanges())
+	require.Equal(t, descpb.SchemaDescriptor_PUBLIC, sc.State)
+	require.Empty(t, sc.OfflineReason)
+	require.Equal(t, "alice", sc.GetOwner())
+
+	populated := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1, State: descpb.SchemaDescriptor_OFFLINE,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"), OfflineReason: "restoring",
+	}
+	sc = schemadesc.NewMutableExisting(populated)
+	require.Zero(t, sc.RunPostDeserializationChanges())
+	require.Zero(t, sc.GetPostDeserializationChanges())
+	require.Equal(t, populated, sc.SchemaDescriptor)
+}
+
+func TestGetPostDeserializationChanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	current := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1, State: descpb.SchemaDescriptor_OFFLINE,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor("alice"), OfflineReason: "restoring",
+	}
+	brokenPrivileges := descpb.NewDefaultPrivilegeDescriptor("alice")
+	brokenPrivileges.Revoke(security.AdminRole, privilege.List{privilege.ALL}, privilege.Schema)
+	for _, tc := range []struct {
+		name     string
+		modify   func(*descpb.SchemaDescriptor)
+		expected catalog.PostDeserializationChanges
+	}{
+		{name: "current", modify: func(*descpb.SchemaDescriptor) {}},
+		{
+			name:     "missing privileges",
+			modify:   func(desc *descpb.SchemaDescriptor) { desc.Privileges = nil },
+			expected: catalog.PostDeserializationChanges{DefaultPrivilegesAdded: true},
+		},
+		{
+			name:     "broken privileges",
+			modify:   func(desc *descpb.SchemaDescriptor) { desc.Privileges = brokenPrivileges },
+			expected: catalog.PostDeserializationChanges{FixedPrivileges: true},
+		},
+		{
+			name:   "unknown state",
+			modify: func(desc *descpb.SchemaDescriptor) { desc.State = descpb.SchemaDescriptor_State(42) },
+			expected: catalog.PostDeserializationChanges{
+				StateNormalized: true, OfflineReasonCleared: true,
+			},
+		},
+		{
+			name:     "stale offline reason",
+			modify:   func(desc *descpb.SchemaDescriptor) { desc.State = descpb.SchemaDescriptor_PUBLIC },
+			expected: catalog.PostDeserializationChanges{OfflineReasonCleared: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			desc := *protoutil.Clone(&current).(*descpb.SchemaDescriptor)
+			tc.modify(&desc)
+			b := schemadesc.NewBuilder(&desc)
+			require.NoError(t, b.RunPostDeserializationChanges(ctx, nil /* dg */))
+			require.Equal(t, tc.expected, b.BuildImmutableSchema().GetPostDeserializationChanges())
+			require.Equal(t, tc.expected, b.BuildExistingMutableSchema().GetPostDeserializationChanges())
+			require.Equal(t, tc.expected, b.BuildCreatedMutableSchema().GetPostDeserializationChanges())
+
+			// The changes match those made by the Mutable method.
+			mut := &schemadesc.Mutable{Immutable: *schemadesc.NewImmutable(desc)}
+			require.Equal(t, tc.expected, mut.RunPostDeserializationChanges())
+			require.Equal(t, tc.expected, mut.GetPostDeserializationChanges())
+			require.Equal(t, b.BuildImmutableSchema().SchemaDescriptor, mut.SchemaDescriptor)
+
+			// Running the changes again makes none, but keeps those recorded.
+			require.Zero(t, mut.RunPostDeserializationChanges())
+			require.Equal(t, tc.expected, mut.GetPostDeserializationChanges())
+		})
+	}
+
+	// Descriptors which were not upgraded report no changes.
+	require.Zero(t, schemadesc.NewImmutable(current).GetPostDeserializationChanges())
+}
+
+func TestGetReferencedDescIDs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	ids, err := sc.GetReferencedDescIDs()
+	require.NoError(t, err)
+	require.Equal(t, []descpb.ID{50}, ids.Ordered())
+
+	sc.RegionAffinityEnumID = 52
+	ids, err = sc.GetReferencedDescIDs()
+	require.NoError(t, err)
+	require.Equal(t, []descpb.ID{50, 52}, ids.Ordered())
+
+	// The set is deduplicated, even for a corrupt descriptor referencing the
+	// same ID twice.
+	sc.RegionAffinityEnumID = 50
+	ids, err = sc.GetReferencedDescIDs()
+	require.NoError(t, err)
+	require.Equal(t, 1, ids.Len())
+	require.True(t, ids.Contains(50))
+}
+
+func TestToSQLString(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name     string
+		owner    string
+		expected string
+	}{
+		{name: "sc", expected: `CREATE SCHEMA sc`},
+		{name: "My Schema", expected: `CREATE SCHEMA "My Schema"`},
+		{name: "select", expected: `CREATE SCHEMA "select"`},
+		{name: `a"b`, expected: `CREATE SCHEMA "a""b"`},
+		{name: "sc", owner: "alice", expected: `CREATE SCHEMA sc AUTHORIZATION alice`},
+		{name: "sc", owner: "Bob", expected: `CREATE SCHEMA sc AUTHORIZATION "Bob"`},
+	} {
+		desc := descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: tc.name}
+		if tc.owner != "" {
+			desc.Privileges = descpb.NewDefaultPrivilegeDescriptor(tc.owner)
+		}
+		require.Equal(t, tc.expected, schemadesc.NewImmutable(desc).ToSQLString())
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	base := descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", Version: 1,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+		DrainingNames: []descpb.NameInfo{
+			{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "a"},
+			{ParentID: 50, ParentSchemaID: keys.RootNamespaceID, Name: "b"},
+		},
+	}
+	fp := schemadesc.NewImmutable(base).Fingerprint()
+
+	same := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+	same.DrainingNames[0], same.DrainingNames[1] = same.DrainingNames[1], same.DrainingNames[0]
+	require.Equal(t, fp, schemadesc.NewImmutable(same).Fingerprint())
+
+	for _, mutate := range []func(*descpb.SchemaDescriptor){
+		func(d *descpb.SchemaDescriptor) { d.Name = "sc2" },
+		func(d *descpb.SchemaDescriptor) { d.Version = 2 },
+		func(d *descpb.SchemaDescriptor) { d.Privileges.SetOwner("alice") },
+		func(d *descpb.SchemaDescriptor) { d.Privileges.Grant("alice", privilege.List{privilege.USAGE}) },
+		func(d *descpb.SchemaDescriptor) { d.DrainingNames = d.DrainingNames[:1] },
+		func(d *descpb.SchemaDescriptor) { d.ModificationTime = hlc.Timestamp{WallTime: 1} },
+		func(d *descpb.SchemaDescriptor) { d.OfflineReason = "restoring" },
+		func(d *descpb.SchemaDescriptor) { d.RegionAffinityEnumID = 60 },
+		func(d *descpb.SchemaDescriptor) { d.ConvertedFromDatabaseID = 52 },
+		func(d *descpb.SchemaDescriptor) { d.DisableAutoStats = true },
+		func(d *descpb.SchemaDescriptor) { comment := "hello"; d.Comment = &comment },
+		func(d *descpb.SchemaDescriptor) { d.DDLLocked = true },
+		func(d *descpb.SchemaDescriptor) { d.DefaultTableTTL = time.Hour },
+		func(d *descpb.SchemaDescriptor) {
+			d.DefaultPrivileges = &descpb.DefaultPrivilegeDescriptor{
+				Objects: []descpb.DefaultPrivilegesForObject{{
+					ObjectType: "table",
+					Users:      []descpb.UserPrivileges{{User: "alice", Privileges: privilege.SELECT.Mask()}},
+				}},
+			}
+		},
+	} {
+		changed := *protoutil.Clone(&base).(*descpb.SchemaDescriptor)
+		mutate(&changed)
+		require.NotEqual(t, fp, schemadesc.NewImmutable(changed).Fingerprint(), "%+v", changed)
+	}
+}
+
+func TestNewTemporarySchema(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tmp := schemadesc.NewTemporarySchema("pg_temp_1_2", 51, 50)
+	require.True(t, tmp.IsTemporary())
+	require.Equal(t, "pg_temp_1_2", tmp.GetName())
+	require.Equal(t, descpb.ID(51), tmp.GetID())
+	require.Equal(t, descpb.ID(50), tmp.GetParentID())
+	require.True(t, tmp.WithoutDrainingNames().IsTemporary())
+
+	var errs catalog.ValidationErrors
+	tmp.ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	// A descriptor which only has a temporary schema's name is not temporary,
+	// and its reserved name fails validation.
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_temp_1_2"})
+	require.False(t, sc.IsTemporary())
+	errs = nil
+	sc.ValidateSelf(&errs)
+	require.Regexp(t, `unacceptable schema name "pg_temp_1_2"`, errs.CombinedError())
+}
+
+func TestNewVirtualSchema(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	vs := schemadesc.NewVirtualSchema(sessiondata.PgCatalogName, 4294967295, 50)
+	require.True(t, vs.IsVirtual())
+	require.Equal(t, catalog.SchemaVirtual, vs.GetSchemaKind())
+	require.Equal(t, sessiondata.PgCatalogName, vs.GetName())
+	require.Equal(t, descpb.ID(4294967295), vs.GetID())
+	require.Equal(t, descpb.ID(50), vs.GetParentID())
+	require.True(t, vs.WithoutDrainingNames().IsVirtual())
+
+	var errs catalog.ValidationErrors
+	vs.ValidateSelf(&errs)
+	require.NoError(t, errs.CombinedError())
+
+	// The descriptor cannot be persisted or modified.
+	require.Panics(t, func() { vs.DescriptorProto() })
+	require.Panics(t, func() { vs.BackupManifestEntry() })
+	require.Panics(t, func() { schemadesc.NewMutableFromImmutable(vs) })
+
+	// Descriptors which merely have a virtual schema's name are not marked
+	// virtual, though they are reported as such.
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: sessiondata.PgCatalogName})
+	require.False(t, sc.IsVirtual())
+	require.Equal(t, catalog.SchemaVirtual, sc.GetSchemaKind())
+	require.NotNil(t, sc.DescriptorProto())
+}
+
+func TestGetSchemaKind(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc catalog.SchemaDescriptor
+		kind catalog.ResolvedSchemaKind
+	}{
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: keys.PublicSchemaID, ParentID: 50, Name: "public"}),
+			kind: catalog.SchemaPublic,
+		},
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "public"}),
+			kind: catalog.SchemaPublic,
+		},
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_catalog"}),
+			kind: catalog.SchemaVirtual,
+		},
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "crdb_internal"}),
+			kind: catalog.SchemaVirtual,
+		},
+		{
+			desc: schemadesc.NewTemporarySchema("pg_temp_1_2", 51, 50),
+			kind: catalog.SchemaTemporary,
+		},
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "pg_temp_1_2"}),
+			kind: catalog.SchemaUserDefined,
+		},
+		{
+			desc: schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}),
+			kind: catalog.SchemaUserDefined,
+		},
+	} {
+		var kind catalog.ResolvedSchemaKind
+		switch sc := tc.desc.(type) {
+		case *schemadesc.Immutable:
+			kind = sc.GetSchemaKind()
+		case *schemadesc.Mutable:
+			kind = sc.GetSchemaKind()
+		}
+		require.Equal(t, tc.kind, kind, tc.desc.GetName())
+	}
+}
+
+func TestDescriptorProtoCached(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc", Version: 2})
+	first := sc.DescriptorProto()
+	require.Same(t, first, sc.DescriptorProto())
+	require.Same(t, &sc.SchemaDescriptor, first.GetSchema())
+	require.Equal(t, "sc", first.GetSchema().Name)
+
+	// A copy of the Immutable does not share the cached wrapper, which points at
+	// the storage of the original.
+	cp := *sc
+	require.Same(t, &cp.SchemaDescriptor, cp.DescriptorProto().GetSchema())
+
+	mut := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.NotSame(t, mut.DescriptorProto(), mut.DescriptorProto())
+	require.NoError(t, mut.SetName("renamed"))
+	require.Equal(t, "renamed", mut.DescriptorProto().GetSchema().Name)
+}
+
+var descriptorProtoSink *descpb.Descriptor
+
+func BenchmarkDescriptorProto(b *testing.B) {
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	b.Run("immutable", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			descriptorProtoSink = sc.DescriptorProto()
+		}
+	})
+	mut := schemadesc.NewMutableExisting(sc.SchemaDescriptor)
+	b.Run("mutable", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			descriptorProtoSink = mut.DescriptorProto()
+		}
+	})
+}
+
+func TestNewImmutableSlice(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	descs := []descpb.SchemaDescriptor{
+		{ID: 51, ParentID: 50, Name: "a", Privileges: descpb.NewDefaultPrivilegeDescriptor("alice")},
+		{ID: 52, ParentID: 50, Name: "b", DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}}},
+		{ID: 53, ParentID: 50, Name: "c"},
+	}
+	scs := schemadesc.NewImmutableSlice(descs)
+	require.Len(t, scs, len(descs))
+	for i, sc := range scs {
+		require.Equal(t, descs[i].ID, sc.GetID())
+		require.Equal(t, descs[i].Name, sc.GetName())
+		require.Equal(t, descs[i], *sc.SchemaDesc())
+	}
+
+	// Mutating the input does not affect the descriptors.
+	descs[0].Name = "changed"
+	descs[0].Privileges.SetOwner("bob")
+	descs[1].DrainingNames[0].Name = "changed"
+	require.Equal(t, "a", scs[0].GetName())
+	require.Equal(t, "alice", scs[0].SchemaDesc().Privileges.Owner)
+	require.Equal(t, "old", scs[1].SchemaDesc().DrainingNames[0].Name)
+
+	require.Empty(t, schemadesc.NewImmutableSlice(nil))
+
+	// The descriptors are the same as those made by NewImmutable.
+	for i, sc := range schemadesc.NewImmutableSlice(descs) {
+		expected := schemadesc.NewImmutable(*protoutil.Clone(&descs[i]).(*descpb.SchemaDescriptor))
+		require.True(t, expected.Equal(sc), descs[i].Name)
+		require.Equal(t, expected.GetPrivileges(), sc.GetPrivileges(), descs[i].Name)
+		require.Equal(t, expected.GetPostDeserializationChanges(),
+			sc.(*schemadesc.Immutable).GetPostDeserializationChanges(), descs[i].Name)
+	}
+}
+
+func BenchmarkNewImmutableSlice(b *testing.B) {
+	descs := make([]descpb.SchemaDescriptor, 100)
+	for i := range descs {
+		descs[i] = descpb.SchemaDescriptor{
+			ID: descpb.ID(51 + i), ParentID: 50, Name: fmt.Sprintf("sc%d", i),
+			Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+		}
+	}
+	b.Run("NewImmutableSlice", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = schemadesc.NewImmutableSlice(descs)
+		}
+	})
+	b.Run("NewImmutable", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ret := make([]catalog.SchemaDescriptor, len(descs))
+			for j := range descs {
+				desc := *protoutil.Clone(&descs[j]).(*descpb.SchemaDescriptor)
+				ret[j] = schemadesc.NewImmutable(desc)
+			}
+		}
+	})
+}
+
+func TestDescriptorType(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.Equal(t, catalog.Schema, sc.DescriptorType())
+	require.Equal(t, sc.DescriptorType(), schemadesc.NewBuilder(sc.SchemaDesc()).DescriptorType())
+	require.NotEqual(t, catalog.Table, sc.DescriptorType())
+	require.NotEqual(t, catalog.Database, sc.DescriptorType())
+}
+
+func TestDefaultPrivileges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewMutableExisting(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.NotNil(t, sc.GetDefaultPrivileges())
+	require.Empty(t, sc.GetDefaultPrivileges().Objects)
+
+	sc.GrantDefaultPrivileges(privilege.Table, "alice", privilege.List{privilege.SELECT, privilege.INSERT})
+	sc.GrantDefaultPrivileges(privilege.Type, "bob", privilege.List{privilege.USAGE})
+	require.Equal(t, []descpb.UserPrivileges{
+		{User: "alice", Privileges: privilege.List{privilege.SELECT, privilege.INSERT}.ToBitField()},
+	}, sc.GetDefaultPrivileges().ForObject(privilege.Table))
+	require.Nil(t, sc.ClusterVersion.DefaultPrivileges)
+
+	// The immutable copy does not share the default privileges.
+	imm := sc.ImmutableCopy().(*schemadesc.Immutable)
+	sc.GrantDefaultPrivileges(privilege.Table, "carl", privilege.List{privilege.SELECT})
+	require.Len(t, imm.GetDefaultPrivileges().ForObject(privilege.Table), 1)
+	require.Len(t, sc.GetDefaultPrivileges().ForObject(privilege.Table), 2)
+
+	sc.RevokeDefaultPrivileges(privilege.Table, "alice", privilege.List{privilege.INSERT})
+	require.Equal(t, []descpb.UserPrivileges{
+		{User: "alice", Privileges: privilege.SELECT.Mask()},
+		{User: "carl", Privileges: privilege.SELECT.Mask()},
+	}, sc.GetDefaultPrivileges().ForObject(privilege.Table))
+
+	sc.RevokeDefaultPrivileges(privilege.Table, "alice", privilege.List{privilege.SELECT})
+	sc.RevokeDefaultPrivileges(privilege.Table, "carl", privilege.List{privilege.ALL})
+	require.Nil(t, sc.GetDefaultPrivileges().ForObject(privilege.Table))
+	sc.RevokeDefaultPrivileges(privilege.Type, "bob", privilege.List{privilege.USAGE})
+	require.Nil(t, sc.DefaultPrivileges)
+}
+
+func TestValidateTxnCommit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	validate := func(mutate func(db *descpb.DatabaseDescriptor)) error {
+		db := dbdesc.NewInitial(50, "db", security.AdminRole).DatabaseDesc()
+		db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{"sc": {ID: 51}}
+		mutate(db)
+		var errs catalog.ValidationErrors
+		sc.ValidateTxnCommit(ctx, catalog.MapDescGetter{50: dbdesc.NewImmutable(*db)}, &errs)
+		return errs.CombinedError()
+	}
+
+	require.NoError(t, validate(func(*descpb.DatabaseDescriptor) {}))
+	// The schema was renamed in the database's schema map but not in its
+	// descriptor.
+	require.Regexp(t, `schema "sc" \(51\) is not in the schemas of its parent database "db" \(50\)`,
+		validate(func(db *descpb.DatabaseDescriptor) {
+			db.Schemas = map[string]descpb.DatabaseDescriptor_SchemaInfo{"renamed": {ID: 51}}
+		}))
+	require.Regexp(t, `schema "sc" \(51\) has dropped parent database "db" \(50\)`,
+		validate(func(db *descpb.DatabaseDescriptor) { db.State = descpb.DatabaseDescriptor_DROP }))
+
+	// Dropped schemas are not checked.
+	dropped := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", State: descpb.SchemaDescriptor_DROP,
+	})
+	var errs catalog.ValidationErrors
+	dropped.ValidateTxnCommit(ctx, catalog.MapDescGetter{}, &errs)
+	require.NoError(t, errs.CombinedError())
+}
+
+func TestGetObjectTypeAndName(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	require.Equal(t, "schema", sc.GetObjectType())
+	require.Equal(t, "sc", sc.GetObjectName())
+}
+
+func TestNewMutableFromImmutable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	imm := schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID:         51,
+		ParentID:   50,
+		Name:       "sc",
+		Version:    3,
+		Privileges: descpb.NewDefaultPrivilegeDescriptor(security.AdminRole),
+	})
+	mut := schemadesc.NewMutableFromImmutable(imm)
+	require.Same(t, imm, mut.ClusterVersion)
+	require.False(t, mut.IsNew())
+	require.True(t, mut.Equal(imm))
+
+	require.NoError(t, mut.SetName("renamed"))
+	mut.MaybeIncrementVersion()
+	mut.Privileges.Grant("testuser", privilege.List{privilege.CREATE})
+	require.Equal(t, "sc", imm.GetName())
+	require.Equal(t, descpb.DescriptorVersion(3), imm.GetVersion())
+	require.Empty(t, imm.GetDrainingNames())
+	require.False(t, imm.Privileges.CheckPrivilege("testuser", privilege.CREATE))
+	require.Equal(t, descpb.DescriptorVersion(4), mut.GetVersion())
+
+	tmp := schemadesc.NewTemporarySchema("pg_temp_1_1", 52, 50)
+	require.True(t, schemadesc.NewMutableFromImmutable(tmp).IsTemporary())
+}
+
+func TestRegionEnumID(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sc := schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"})
+	id, ok := sc.RegionEnumID()
+	require.False(t, ok)
+	require.Equal(t, descpb.InvalidID, id)
+
+	sc = schemadesc.NewImmutable(descpb.SchemaDescriptor{
+		ID: 51, ParentID: 50, Name: "sc", RegionAffinityEnumID: 52,
+	})
+	id, ok = sc.RegionEnumID()
+	require.True(t, ok)
+	require.Equal(t, descpb.ID(52), id)
+}
+
+func TestSafeForFollowerRead(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		name     string
+		desc     *schemadesc.Immutable
+		expected bool
+	}{
+		{
+			name: "public",
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: keys.PublicSchemaID, ParentID: 50, Name: "public",
+			}),
+			expected: true,
+		},
+		{
+			name:     "virtual",
+			desc:     schemadesc.NewVirtualSchema(sessiondata.PgCatalogName, 4294967295, 50),
+			expected: true,
+		},
+		{
+			name:     "temporary",
+			desc:     schemadesc.NewTemporarySchema("pg_temp_1_1", 51, 50),
+			expected: true,
+		},
+		{
+			name:     "user defined",
+			desc:     schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}),
+			expected: true,
+		},
+		{
+			name: "renamed",
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc",
+				DrainingNames: []descpb.NameInfo{{ParentID: 50, Name: "old"}},
+			}),
+			expected: false,
+		},
+		{
+			name: "dropped",
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc", State: descpb.SchemaDescriptor_DROP,
+			}),
+			expected: false,
+		},
+		{
+			name: "offline",
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: 51, ParentID: 50, Name: "sc", State: descpb.SchemaDescriptor_OFFLINE,
+			}),
+			expected: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.desc.SafeForFollowerRead())
+		})
+	}
+}
+
+func TestTelemetryCounters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		desc     *schemadesc.Immutable
+		expected map[string]int
+	}{
+		{
+			desc:     schemadesc.NewImmutable(descpb.SchemaDescriptor{ID: 51, ParentID: 50, Name: "sc"}),
+			expected: map[string]int{"user_schema": 1},
+		},
+		{
+			desc: schemadesc.NewImmutable(descpb.SchemaDescriptor{
+				ID: keys.PublicSchemaID, ParentID: 50, Name: "public",
+			}),
+			expected: map[string]int{"public_schema": 1},
+		},
+		{
+			desc:     schemadesc.NewVirtualSchema(sessiondata.PgCatalogName, 4294967295, 50),
+			expected: map[string]int{"virtual_schema": 1},
+		},
+		{
+			desc:     schemadesc.NewTemporarySchema("pg_temp_1_1", 51, 50),
+			expected: map[string]int{"temp_schema": 1},
+		},
+	} {
+		require.Equal(t, tc.expected, tc.desc.TelemetryCounters(), tc.desc.GetName())
+	}
+}
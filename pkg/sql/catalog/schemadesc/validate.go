@@ -0,0 +1,489 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/lex"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+var (
+	// ErrSchemaAlreadyDropped marks errors returned when dropping a schema which
+	// is already being dropped.
+	ErrSchemaAlreadyDropped = errors.New("schema is already being dropped")
+	// ErrSchemaNotPublic marks errors returned when dropping a schema which is
+	// still being added or is offline.
+	ErrSchemaNotPublic = errors.New("schema is not public")
+	// ErrSchemaCannotBeDropped marks errors returned when dropping a schema
+	// which is not user defined, such as the public or a virtual schema.
+	ErrSchemaCannotBeDropped = errors.New("schema cannot be dropped")
+	// ErrSchemaNotEmpty marks errors returned when dropping a schema which
+	// still contains objects without CASCADE.
+	ErrSchemaNotEmpty = errors.New("schema is not empty")
+)
+
+// ValidateSelf validates the schema descriptor in isolation, without looking
+// up any other descriptors. All problems found are reported to vea.
+func (desc *Immutable) ValidateSelf(vea catalog.ValidationErrorAccumulator) {
+	desc.ValidateIdentity(vea)
+	if desc.Name != "" && desc.GetSchemaKind() == catalog.SchemaUserDefined {
+		if err := IsSchemaNameValid(desc.Name); err != nil {
+			vea.Report(err)
+		}
+	}
+	desc.validatePrivilegeVersion(vea)
+	desc.validateOwner(vea)
+	desc.validateConvertedFromDatabase(vea)
+	desc.validateDrainingNames(vea)
+	if desc.GetSchemaKind() == catalog.SchemaTemporary {
+		if _, ok := desc.TemporarySessionID(); !ok {
+			vea.Report(errors.Newf("temporary schema %q (%d) does not encode a valid session ID",
+				desc.Name, errors.Safe(desc.ID)))
+		}
+	}
+	if ttl := desc.DefaultTableTTL; ttl < 0 {
+		vea.Report(errors.Newf("schema %q (%d) has negative default table TTL %s",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(ttl)))
+	} else if ttl > 0 && desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		vea.Report(errors.Newf("schema %q (%d) cannot have a default table TTL",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+	if desc.DDLLocked && desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		vea.Report(errors.Newf("schema %q (%d) cannot be locked against DDL",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+	if desc.DisableAutoStats && desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		vea.Report(errors.Newf("automatic statistics cannot be disabled for schema %q (%d)",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+}
+
+// ValidateIdentity validates the parts of the schema descriptor without which
+// it cannot be used at all: its name must be non-empty, and its ID and parent
+// ID must be valid. This is the subset of ValidateSelf which is checked when
+// descriptors are read from storage. The other rules were introduced after
+// schemas were first persisted, and a stored schema violating one of them
+// must remain readable so that it can be repaired, e.g. renamed or
+// reassigned.
+func (desc *Immutable) ValidateIdentity(vea catalog.ValidationErrorAccumulator) {
+	if err := catalog.ValidateName(desc.Name, "schema"); err != nil {
+		vea.Report(err)
+	}
+	if desc.ID == descpb.InvalidID {
+		vea.Report(errors.AssertionFailedf("schema %q has invalid ID %d",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+	if desc.ParentID == descpb.InvalidID {
+		vea.Report(errors.AssertionFailedf("schema %q (%d) has invalid parent ID %d",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(desc.ParentID)))
+	} else if desc.ID == desc.ParentID {
+		vea.Report(errors.AssertionFailedf("schema %q (%d) has the same ID as its parent database",
+			desc.Name, errors.Safe(desc.ID)))
+	}
+}
+
+// validatePrivilegeVersion checks that the privilege descriptor was written
+// with a version which this binary understands. An older node must not
+// rewrite a descriptor whose privilege bits it may not know about.
+func (desc *Immutable) validatePrivilegeVersion(vea catalog.ValidationErrorAccumulator) {
+	if desc.Privileges == nil {
+		return
+	}
+	// OwnerVersion is the newest privilege descriptor version.
+	if v := desc.Privileges.Version; v > descpb.OwnerVersion {
+		vea.Report(errors.Newf(
+			"schema %q (%d) has privilege descriptor version %d, but the newest supported version is %d",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(v), errors.Safe(descpb.OwnerVersion)))
+	}
+}
+
+// reassignOwnerHint is the hint of the errors reported for a schema whose
+// owner is not a genuine SQL user.
+const reassignOwnerHint = "use REASSIGN OWNED or ALTER SCHEMA ... OWNER TO to assign it to a SQL user"
+
+// validateOwner checks that the schema is owned by a genuine SQL user: not
+// the public pseudo-role, which cannot own anything, and, for a user defined
+// schema, not the internal node user, as no one could then alter it. Such a
+// schema should be reassigned to a real owner.
+func (desc *Immutable) validateOwner(vea catalog.ValidationErrorAccumulator) {
+	if desc.Privileges == nil {
+		return
+	}
+	switch owner := desc.Privileges.Owner; {
+	case owner == security.PublicRole:
+		vea.Report(errors.WithHint(
+			errors.Newf("schema %q (%d) is owned by the %q role, which cannot own objects",
+				desc.Name, errors.Safe(desc.ID), security.PublicRole),
+			reassignOwnerHint))
+	case owner == security.NodeUser && desc.GetSchemaKind() == catalog.SchemaUserDefined:
+		vea.Report(errors.WithHint(
+			errors.Newf("schema %q (%d) is owned by the internal user %q",
+				desc.Name, errors.Safe(desc.ID), security.NodeUser),
+			reassignOwnerHint))
+	}
+}
+
+// validateConvertedFromDatabase checks that the ID of the database from which
+// the schema was converted, if any, is plausible. Only user defined schemas
+// are produced by converting a database, and the converted database can be
+// neither the schema itself nor its new parent database.
+func (desc *Immutable) validateConvertedFromDatabase(vea catalog.ValidationErrorAccumulator) {
+	id := desc.GetConvertedFromDatabaseID()
+	if id == descpb.InvalidID {
+		return
+	}
+	if desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		vea.Report(errors.Newf("schema %q (%d) cannot be converted from database %d",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(id)))
+		return
+	}
+	if id == desc.ID || id == desc.ParentID {
+		vea.Report(errors.Newf("schema %q (%d) with parent %d has invalid converted database ID %d",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(desc.ParentID), errors.Safe(id)))
+	}
+}
+
+// validateDrainingNames checks that the schema's current name is not also
+// draining, and that no name is draining more than once. Either indicates a
+// bug in rename handling, and would cause the namespace entry of the live name
+// to be removed once the draining names are cleaned up.
+func (desc *Immutable) validateDrainingNames(vea catalog.ValidationErrorAccumulator) {
+	live := descpb.NameInfo{
+		ParentID:       desc.ParentID,
+		ParentSchemaID: keys.RootNamespaceID,
+		Name:           desc.Name,
+	}
+	seen := make(map[descpb.NameInfo]struct{}, len(desc.DrainingNames))
+	for _, drain := range desc.DrainingNames {
+		if drain == live {
+			vea.Report(errors.AssertionFailedf("schema %q (%d) has its current name as a draining name",
+				desc.Name, errors.Safe(desc.ID)))
+		}
+		if _, ok := seen[drain]; ok {
+			vea.Report(errors.AssertionFailedf("schema %q (%d) has duplicate draining name %q",
+				desc.Name, errors.Safe(desc.ID), drain.Name))
+			continue
+		}
+		seen[drain] = struct{}{}
+	}
+}
+
+// ValidateCrossReferences validates the schema descriptor against the other
+// catalog state it refers to, including the roles named by its privileges,
+// whose existence is checked using roles. All problems found are reported to
+// vea.
+func (desc *Immutable) ValidateCrossReferences(
+	ctx context.Context,
+	vdg catalog.ValidationDescGetter,
+	roles catalog.RoleExistenceChecker,
+	vea catalog.ValidationErrorAccumulator,
+) {
+	desc.validateParentDatabase(ctx, vdg, vea)
+	desc.validateNamespaceEntry(ctx, vdg, vea)
+	desc.validateRolesExist(ctx, roles, vea)
+}
+
+// validateRolesExist checks that the owner of the schema and every grantee of
+// its privileges is an existing role, so that a schema left referring to a
+// dropped role is caught. The root user and the admin and public roles always
+// exist, as does the internal node user.
+func (desc *Immutable) validateRolesExist(
+	ctx context.Context, checker catalog.RoleExistenceChecker, vea catalog.ValidationErrorAccumulator,
+) {
+	if desc.Privileges == nil {
+		return
+	}
+	exists := func(role string) bool {
+		switch role {
+		case security.RootUser, security.AdminRole, security.PublicRole, security.NodeUser:
+			return true
+		}
+		ok, err := checker.RoleExists(ctx, role)
+		if err != nil {
+			vea.Report(err)
+			return true
+		}
+		return ok
+	}
+	if owner := desc.Privileges.Owner; owner != "" && !exists(owner) {
+		vea.Report(errors.Newf("schema %q (%d) is owned by role %q, which does not exist",
+			desc.Name, errors.Safe(desc.ID), owner))
+	}
+	for _, u := range desc.Privileges.Users {
+		if !exists(u.User) {
+			vea.Report(errors.Newf("schema %q (%d) grants privileges to role %q, which does not exist",
+				desc.Name, errors.Safe(desc.ID), u.User))
+		}
+	}
+}
+
+// ValidateTxnCommit validates the schema descriptor as it is about to be
+// committed, against the other descriptors as modified by the transaction.
+// Unlike the checks of ValidateSelf, these only need to hold at commit time:
+// a live user defined schema must still be referenced under its current name
+// by its parent database, which must not have been dropped in the meantime.
+// All problems found are reported to vea.
+func (desc *Immutable) ValidateTxnCommit(
+	ctx context.Context, vdg catalog.ValidationDescGetter, vea catalog.ValidationErrorAccumulator,
+) {
+	if desc.Dropped() || desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		return
+	}
+	desc.validateParentDatabase(ctx, vdg, vea)
+}
+
+// validateParentDatabase checks that the parent of the schema is a live
+// database and, for user defined schemas, that the database's schema map
+// refers back to the schema under its name.
+func (desc *Immutable) validateParentDatabase(
+	ctx context.Context, vdg catalog.ValidationDescGetter, vea catalog.ValidationErrorAccumulator,
+) {
+	// Virtual schemas are synthesized for every database.
+	if desc.GetSchemaKind() == catalog.SchemaVirtual {
+		return
+	}
+	parent, err := vdg.GetDesc(ctx, desc.ParentID)
+	if err != nil {
+		vea.Report(err)
+		return
+	}
+	if parent == nil {
+		vea.Report(errors.AssertionFailedf("schema %q (%d) has missing parent database %d",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(desc.ParentID)))
+		return
+	}
+	db, ok := parent.(catalog.DatabaseDescriptor)
+	if !ok {
+		vea.Report(errors.AssertionFailedf("schema %q (%d) has parent %d which is a %s, not a database",
+			desc.Name, errors.Safe(desc.ID), errors.Safe(desc.ParentID), errors.Safe(parent.TypeName())))
+		return
+	}
+	// A dropped schema may outlive its parent until it is cleaned up.
+	if desc.Dropped() {
+		return
+	}
+	if db.Dropped() {
+		vea.Report(errors.Newf("schema %q (%d) has dropped parent database %q (%d)",
+			desc.Name, errors.Safe(desc.ID), db.GetName(), errors.Safe(db.GetID())))
+	} else if db.Offline() {
+		vea.Report(errors.Newf("schema %q (%d) has offline parent database %q (%d)",
+			desc.Name, errors.Safe(desc.ID), db.GetName(), errors.Safe(db.GetID())))
+	}
+	if desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		return
+	}
+	schemas := db.DatabaseDesc().Schemas
+	if info, ok := schemas[desc.Name]; !ok {
+		vea.Report(errors.Newf("schema %q (%d) is not in the schemas of its parent database %q (%d)",
+			desc.Name, errors.Safe(desc.ID), db.GetName(), errors.Safe(db.GetID())))
+	} else if info.ID != desc.ID || info.Dropped {
+		vea.Report(errors.Newf(
+			"schema %q (%d) is not in the schemas of its parent database %q (%d): entry has ID %d, dropped=%t",
+			desc.Name, errors.Safe(desc.ID), db.GetName(), errors.Safe(db.GetID()),
+			errors.Safe(info.ID), errors.Safe(info.Dropped)))
+	}
+	// Conversely, any other name mapped to the schema must be one of its names
+	// still being drained, in which case it is marked as dropped.
+	var otherNames []string
+	for name, info := range schemas {
+		if info.ID == desc.ID && name != desc.Name {
+			otherNames = append(otherNames, name)
+		}
+	}
+	sort.Strings(otherNames)
+	for _, name := range otherNames {
+		info := schemas[name]
+		if info.Dropped && desc.isDrainingName(name) {
+			continue
+		}
+		vea.Report(errors.Newf(
+			"parent database %q (%d) has stale entry %q (dropped=%t) for schema %q (%d) in state %s with %d draining names",
+			db.GetName(), errors.Safe(db.GetID()), name, errors.Safe(info.Dropped),
+			desc.Name, errors.Safe(desc.ID), errors.Safe(desc.State), errors.Safe(len(desc.DrainingNames))))
+	}
+}
+
+// isDrainingName returns whether name is a draining name of the schema.
+func (desc *Immutable) isDrainingName(name string) bool {
+	for _, drain := range desc.DrainingNames {
+		if drain.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNamespaceEntry checks that the system.namespace entry of the schema
+// carries exactly the same name as the descriptor. Name resolution is
+// case-sensitive on the stored key, so an entry differing only in case is a
+// corruption even though the names look alike.
+func (desc *Immutable) validateNamespaceEntry(
+	ctx context.Context, vdg catalog.ValidationDescGetter, vea catalog.ValidationErrorAccumulator,
+) {
+	// Public and virtual schemas have no namespace entry of their own in this
+	// version, and dropped schemas may have already lost theirs.
+	if desc.Dropped() || desc.ID == keys.PublicSchemaID || desc.GetSchemaKind() == catalog.SchemaVirtual {
+		return
+	}
+	entries, err := vdg.GetNamespaceEntries(ctx, desc.ParentID, keys.RootNamespaceID)
+	if err != nil {
+		vea.Report(err)
+		return
+	}
+	if entries[desc.Name] == desc.ID {
+		return
+	}
+	normalized := lex.NormalizeName(desc.Name)
+	for name, id := range entries {
+		if id == desc.ID && lex.NormalizeName(name) == normalized {
+			vea.Report(errors.Newf("schema %q (%d) has namespace entry %q which differs in case",
+				desc.Name, errors.Safe(desc.ID), name))
+			return
+		}
+	}
+	vea.Report(errors.Newf("schema %q (%d) has no namespace entry with the same name",
+		desc.Name, errors.Safe(desc.ID)))
+}
+
+// ValidateDropPreconditions checks that the schema can be dropped with the
+// given behavior. The schema must be public, must be user defined and, unless
+// the drop cascades, must not contain any objects; the default behavior is
+// RESTRICT. Each failure is marked with one of the ErrSchema* sentinels above
+// so that callers can map it to a user-facing message using errors.Is.
+func (desc *Immutable) ValidateDropPreconditions(
+	ctx context.Context, vdg catalog.ValidationDescGetter, behavior tree.DropBehavior,
+) error {
+	switch desc.State {
+	case descpb.SchemaDescriptor_PUBLIC:
+	case descpb.SchemaDescriptor_DROP:
+		return errors.Mark(pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"schema %q is already being dropped", desc.Name), ErrSchemaAlreadyDropped)
+	default:
+		return errors.Mark(pgerror.Newf(pgcode.ObjectNotInPrerequisiteState,
+			"schema %q is not public", desc.Name), ErrSchemaNotPublic)
+	}
+	if desc.GetSchemaKind() != catalog.SchemaUserDefined {
+		return errors.Mark(pgerror.Newf(pgcode.InvalidSchemaName,
+			"cannot drop schema %q", desc.Name), ErrSchemaCannotBeDropped)
+	}
+	if behavior == tree.DropCascade {
+		return nil
+	}
+	objectIDs, err := vdg.GetObjectIDsInSchema(ctx, desc.ParentID, desc.ID)
+	if err != nil {
+		return err
+	}
+	if len(objectIDs) > 0 {
+		return errors.Mark(pgerror.Newf(pgcode.DependentObjectsStillExist,
+			"schema %q is not empty and CASCADE was not specified", desc.Name), ErrSchemaNotEmpty)
+	}
+	return nil
+}
+
+// UserSchemasInDatabase returns the names of the user defined schemas in db
+// which are not being dropped, in sorted order. DROP DATABASE ... RESTRICT
+// must fail if this list is non-empty.
+func UserSchemasInDatabase(
+	ctx context.Context, db catalog.DatabaseDescriptor, vdg catalog.ValidationDescGetter,
+) ([]string, error) {
+	var names []string
+	for name, info := range db.DatabaseDesc().Schemas {
+		if info.Dropped {
+			continue
+		}
+		desc, err := vdg.GetDesc(ctx, info.ID)
+		if err != nil {
+			return nil, err
+		}
+		schema, ok := desc.(catalog.SchemaDescriptor)
+		if !ok {
+			return nil, errors.AssertionFailedf(
+				"schema %q (%d) of database %q (%d) is not a schema descriptor",
+				name, errors.Safe(info.ID), db.GetName(), errors.Safe(db.GetID()))
+		}
+		sc := NewImmutable(*schema.SchemaDesc())
+		if sc.Dropped() || sc.GetSchemaKind() != catalog.SchemaUserDefined {
+			continue
+		}
+		names = append(names, sc.GetName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ValidateDatabaseToSchemaConversion checks the outcome of converting the
+// database db into the schema newSchema. The schema must record db as the
+// database it was converted from, and every object of db must have been
+// reparented to newSchema: no object may remain in any schema of db, and the
+// objects found in newSchema must refer to it and to its parent database.
+func ValidateDatabaseToSchemaConversion(
+	ctx context.Context,
+	db catalog.DatabaseDescriptor,
+	newSchema catalog.SchemaDescriptor,
+	vdg catalog.ValidationDescGetter,
+) error {
+	var errs catalog.ValidationErrors
+	sc := newSchema.SchemaDesc()
+	if sc.ConvertedFromDatabaseID != db.GetID() {
+		errs.Report(errors.Newf("schema %q (%d) is not recorded as converted from database %q (%d)",
+			sc.Name, errors.Safe(sc.ID), db.GetName(), errors.Safe(db.GetID())))
+	}
+
+	// Look for objects left behind in the public schema or in any of the user
+	// defined schemas of the converted database.
+	oldSchemaIDs := descpb.IDs{keys.PublicSchemaID}
+	for _, info := range db.DatabaseDesc().Schemas {
+		oldSchemaIDs = append(oldSchemaIDs, info.ID)
+	}
+	sort.Sort(oldSchemaIDs)
+	for _, schemaID := range oldSchemaIDs {
+		ids, err := vdg.GetObjectIDsInSchema(ctx, db.GetID(), schemaID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			errs.Report(errors.Newf("object %d is still in schema %d of converted database %q (%d)",
+				errors.Safe(id), errors.Safe(schemaID), db.GetName(), errors.Safe(db.GetID())))
+		}
+	}
+
+	ids, err := vdg.GetObjectIDsInSchema(ctx, sc.ParentID, sc.ID)
+	if err != nil {
+		return err
+	}
+	objects, err := vdg.GetDescs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, obj := range objects {
+		if obj == nil {
+			errs.Report(errors.AssertionFailedf("object %d in schema %q (%d) not found",
+				errors.Safe(ids[i]), sc.Name, errors.Safe(sc.ID)))
+			continue
+		}
+		if obj.GetParentID() != sc.ParentID || obj.GetParentSchemaID() != sc.ID {
+			errs.Report(errors.Newf("object %q (%d) has parent %d and parent schema %d, expected %d and %d",
+				obj.GetName(), errors.Safe(obj.GetID()), errors.Safe(obj.GetParentID()),
+				errors.Safe(obj.GetParentSchemaID()), errors.Safe(sc.ParentID), errors.Safe(sc.ID)))
+		}
+	}
+	return errs.CombinedError()
+}
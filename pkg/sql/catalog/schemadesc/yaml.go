@@ -0,0 +1,89 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package schemadesc
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// schemaYAML is the canonical YAML representation of a schema, as produced
+// by ToYAML and consumed by FromYAML. It only contains the parts of the
+// descriptor which are managed declaratively; IDs, versions and other
+// bookkeeping are omitted.
+type schemaYAML struct {
+	Name       string          `yaml:"name"`
+	Owner      string          `yaml:"owner"`
+	Privileges []privilegeYAML `yaml:"privileges,omitempty"`
+}
+
+// privilegeYAML lists the privileges granted to a user on the schema.
+type privilegeYAML struct {
+	User       string   `yaml:"user"`
+	Privileges []string `yaml:"privileges,flow"`
+}
+
+// ToYAML returns the canonical YAML representation of the schema: its name,
+// owner and privileges. Users and privilege names are sorted so that equal
+// schemas always produce identical output. A schema without privileges or
+// without an owner is reported as owned by the admin role.
+func (desc *Immutable) ToYAML() ([]byte, error) {
+	// Legacy descriptors may lack privileges or an owner; they are normalized
+	// the same way as when reading them, so that the output can be parsed
+	// back by FromYAML.
+	out := schemaYAML{Name: desc.Name, Owner: desc.GetOwner()}
+	for _, u := range desc.GetPrivileges().Users {
+		out.Privileges = append(out.Privileges, privilegeYAML{
+			User:       u.User,
+			Privileges: privilege.ListFromBitField(u.Privileges, privilege.Schema).SortedNames(),
+		})
+	}
+	sort.Slice(out.Privileges, func(i, j int) bool {
+		return out.Privileges[i].User < out.Privileges[j].User
+	})
+	return yaml.Marshal(&out)
+}
+
+// FromYAML parses the canonical YAML representation of a schema produced by
+// ToYAML. Unknown keys are rejected. The returned descriptor is a new schema
+// without an ID or parent database, which the caller needs to fill in.
+func FromYAML(in []byte) (*Mutable, error) {
+	var parsed schemaYAML
+	if err := yaml.UnmarshalStrict(in, &parsed); err != nil {
+		return nil, errors.Wrap(err, "parsing schema YAML")
+	}
+	if err := IsSchemaNameValid(parsed.Name); err != nil {
+		return nil, err
+	}
+	if parsed.Owner == "" {
+		return nil, errors.Newf("schema %q has no owner", parsed.Name)
+	}
+	privs := &descpb.PrivilegeDescriptor{Owner: parsed.Owner, Version: descpb.OwnerVersion}
+	for _, p := range parsed.Privileges {
+		list, err := privilege.ListFromStrings(p.Privileges)
+		if err != nil {
+			return nil, errors.Wrapf(err, "privileges of user %q", p.User)
+		}
+		if err := privilege.ValidatePrivileges(list, privilege.Schema); err != nil {
+			return nil, errors.Wrapf(err, "privileges of user %q", p.User)
+		}
+		privs.Grant(p.User, list)
+	}
+	return NewMutableCreatedSchemaDescriptor(descpb.SchemaDescriptor{
+		Name:       parsed.Name,
+		Version:    1,
+		Privileges: privs,
+	}), nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package catalog
+
+import "github.com/cockroachdb/errors"
+
+// ValidationErrorAccumulator is used by descriptor validation methods to
+// report the problems they find. Reporting through an accumulator rather than
+// returning the first error lets validation surface every problem with a
+// descriptor at once.
+type ValidationErrorAccumulator interface {
+	// Report adds err to the accumulated errors. Nil errors are ignored.
+	Report(err error)
+}
+
+// ValidationErrors is a ValidationErrorAccumulator which collects the
+// reported errors in a slice.
+type ValidationErrors []error
+
+var _ ValidationErrorAccumulator = (*ValidationErrors)(nil)
+
+// Report implements the ValidationErrorAccumulator interface.
+func (ve *ValidationErrors) Report(err error) {
+	if err != nil {
+		*ve = append(*ve, err)
+	}
+}
+
+// CombinedError returns all of the accumulated errors combined into one, or
+// nil if no errors were reported.
+func (ve ValidationErrors) CombinedError() error {
+	var combined error
+	for _, err := range ve {
+		combined = errors.CombineErrors(combined, err)
+	}
+	return combined
+}
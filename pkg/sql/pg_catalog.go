@@ -3267,7 +3267,7 @@ type oidTypeTag uint8
 
 const (
 	_ oidTypeTag = iota
-	namespaceTypeTag
+	_ // namespaceTypeTag, see schemadesc.NamespaceOID.
 	indexTypeTag
 	columnTypeTag
 	checkConstraintTypeTag
@@ -3318,10 +3318,7 @@ func (h oidHasher) writeForeignKeyConstraint(fk *descpb.ForeignKeyConstraint) {
 }
 
 func (h oidHasher) NamespaceOid(dbID descpb.ID, scName string) *tree.DOid {
-	h.writeTypeTag(namespaceTypeTag)
-	h.writeDB(dbID)
-	h.writeSchema(scName)
-	return h.getOid()
+	return tree.NewDOid(tree.DInt(schemadesc.NamespaceOID(dbID, scName)))
 }
 
 func (h oidHasher) IndexOid(tableID descpb.ID, indexID descpb.IndexID) *tree.DOid {
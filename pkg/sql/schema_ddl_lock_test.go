@@ -0,0 +1,105 @@
+// Copyright 2020 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/schemadesc"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// TestSchemaDDLLocked checks that DDL on a schema locked for maintenance, and
+// on the objects in it, is rejected while queries keep working. There is no
+// SQL syntax for the lock, so the schema descriptor is written directly.
+func TestSchemaDDLLocked(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+	// Session variables must apply to every statement.
+	sqlDB.SetMaxOpenConns(1)
+	r := sqlutils.MakeSQLRunner(sqlDB)
+
+	r.Exec(t, `
+SET experimental_enable_user_defined_schemas = true;
+SET experimental_enable_enums = true;
+CREATE DATABASE d;
+USE d;
+CREATE SCHEMA sc;
+CREATE TABLE sc.t (a INT PRIMARY KEY);
+CREATE TABLE public.u (a INT PRIMARY KEY);
+`)
+
+	dbID := descpb.ID(sqlutils.QueryDatabaseID(t, sqlDB, "d"))
+	setLocked := func(locked bool) {
+		if err := kvDB.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+			db, err := catalogkv.MustGetDatabaseDescByID(ctx, txn, keys.SystemSQLCodec, dbID)
+			if err != nil {
+				return err
+			}
+			desc, err := catalogkv.GetAnyDescriptorByID(ctx, txn, keys.SystemSQLCodec,
+				db.Schemas["sc"].ID, catalogkv.Mutable)
+			if err != nil {
+				return err
+			}
+			sc := desc.(*schemadesc.Mutable)
+			sc.SetDDLLocked(locked)
+			sc.MaybeIncrementVersion()
+			b := txn.NewBatch()
+			if err := catalogkv.WriteDescToBatch(
+				ctx, false /* kvTrace */, s.ClusterSettings(), b, keys.SystemSQLCodec, sc.GetID(), sc,
+			); err != nil {
+				return err
+			}
+			return txn.Run(ctx, b)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setLocked(true)
+
+	const lockedErr = `schema "sc" is locked for maintenance`
+	for _, stmt := range []string{
+		`ALTER TABLE sc.t ADD COLUMN b INT`,
+		`ALTER TABLE sc.t RENAME TO t2`,
+		`CREATE INDEX ON sc.t (a)`,
+		`DROP TABLE sc.t`,
+		`CREATE TABLE sc.t2 (a INT)`,
+		`CREATE VIEW sc.v AS SELECT 1`,
+		`CREATE SEQUENCE sc.s`,
+		`CREATE TYPE sc.typ AS ENUM ('a')`,
+		`ALTER TABLE public.u SET SCHEMA sc`,
+		`ALTER SCHEMA sc RENAME TO sc2`,
+		`DROP SCHEMA sc CASCADE`,
+	} {
+		r.ExpectErr(t, lockedErr, stmt)
+	}
+
+	// Queries still work.
+	r.Exec(t, `INSERT INTO sc.t VALUES (1)`)
+	r.CheckQueryResults(t, `SELECT a FROM sc.t`, [][]string{{"1"}})
+
+	setLocked(false)
+	r.Exec(t, `ALTER TABLE sc.t ADD COLUMN b INT`)
+	r.Exec(t, `DROP TABLE sc.t`)
+}
@@ -74,6 +74,13 @@ var (
 	TempObjectCleanerDeletionCounter = telemetry.GetCounterOnce("sql.schema.temp_object_cleaner.num_cleaned")
 )
 
+// InvalidSchemaNameCounterPrefix is the common prefix of the telemetry keys
+// carried by the errors returned when a schema name is rejected, which are
+// suffixed with the rule which rejected the name. When such an error reaches
+// the client, telemetry.RecordError counts the key as
+// othererror.<pgcode>.<key>.
+const InvalidSchemaNameCounterPrefix = "sql.schema.invalid_schema_name."
+
 // SchemaNewColumnTypeQualificationCounter is to be incremented every time
 // a new qualification is used for a newly created column.
 func SchemaNewColumnTypeQualificationCounter(qual string) telemetry.Counter {
@@ -16,6 +16,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
@@ -204,7 +205,11 @@ func (p *planner) GetAllRoles(ctx context.Context) (map[string]bool, error) {
 	return users, nil
 }
 
-// RoleExists returns true if the role exists.
+var _ catalog.RoleExistenceChecker = &planner{}
+
+// RoleExists returns true if the role exists. It implements the
+// catalog.RoleExistenceChecker interface, so that the planner can be used
+// to validate the roles referred to by descriptors.
 func (p *planner) RoleExists(ctx context.Context, role string) (bool, error) {
 	query := `SELECT username FROM system.users WHERE username = $1`
 	row, err := p.ExtendedEvalContext().ExecCfg.InternalExecutor.QueryRowEx(